@@ -0,0 +1,251 @@
+// Package backfill finds the hourly files that are missing or about to expire and builds them
+// again, so a failed run doesn't leave an hour without a file until the next day.
+package backfill
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/generator"
+	"WiiNewsPR/sink"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"sort"
+	"time"
+)
+
+// DefaultMargin is how long before its EndTimestamp a file is rebuilt. It is at least as long as
+// the gap between backfills, so consoles never hold on to an expired file.
+const DefaultMargin = time.Hour
+
+// ErrNoArticles is returned for an hour when the cache has no article set recent enough to build
+// its file from.
+var ErrNoArticles = errors.New("no cached articles recent enough")
+
+// Backfill checks the 24 hourly files in a sink.
+type Backfill struct {
+	// Options configures generation. Its clock and first time zone decide the current hour and
+	// which files expired.
+	// Its output is replaced by Sink.
+	Options generator.Options
+	// Sink holds the files and receives the rebuilt ones.
+	Sink sink.Sink
+	// Margin rebuilds files that expire within it. Defaults to DefaultMargin.
+	Margin time.Duration
+}
+
+// Fill is a file that was built again.
+type Fill struct {
+	Hour int
+	// Reason is why the file was rebuilt.
+	Reason string
+	// Slot is when the cached articles reused were generated. It is zero when they were fetched.
+	Slot   time.Time
+	Result generator.Result
+}
+
+func (f Fill) String() string {
+	if f.Slot.IsZero() {
+		return fmt.Sprintf("hour %02d (%s): generated with fresh articles", f.Hour, f.Reason)
+	}
+	return fmt.Sprintf("hour %02d (%s): rebuilt from the articles of %s", f.Hour, f.Reason, f.Slot.Format(time.RFC3339))
+}
+
+func (b *Backfill) setDefaults() {
+	if b.Options.LanguageCode == 0 {
+		b.Options.LanguageCode = generator.DefaultLanguageCode
+	}
+
+	if b.Options.CountryCode == 0 {
+		b.Options.CountryCode = generator.DefaultCountryCode
+	}
+
+	if b.Options.Clock == nil {
+		b.Options.Clock = generator.SystemClock{}
+	}
+
+	// Generate and Replay must see the same cache as the slots picked here.
+	if b.Options.CacheStore == nil {
+		b.Options.CacheStore = cache.NewDirStore(b.Options.CacheDir)
+	}
+
+	if b.Margin <= 0 {
+		b.Margin = DefaultMargin
+	}
+
+	b.Options.Output = b.Sink
+}
+
+// key is where the file for hour is kept in the sink.
+func (b *Backfill) key(hour int) string {
+	return generator.Result{LanguageCode: b.Options.LanguageCode, CountryCode: b.Options.CountryCode, Hour: hour}.Path()
+}
+
+// Check returns the hours whose file needs to be built again, with the reason. A file does if it
+// is missing, can't be decoded or expires within Margin. The current hour's file also does if it
+// was generated before the hour started.
+func (b *Backfill) Check(ctx context.Context) (map[int]string, error) {
+	b.setDefaults()
+
+	now := b.Options.LocalNow()
+	stale := map[int]string{}
+	for hour := 0; hour < 24; hour++ {
+		data, err := b.Sink.Read(ctx, b.key(hour))
+		if errors.Is(err, fs.ErrNotExist) {
+			stale[hour] = "missing"
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", b.key(hour), err)
+		}
+
+		parsed, err := generator.DecodeFile(data)
+		if err != nil {
+			stale[hour] = fmt.Sprintf("unreadable: %v", err)
+			continue
+		}
+
+		end := generator.ParseTimestamp(parsed.Header.EndTimestamp)
+		updated := generator.ParseTimestamp(parsed.Header.UpdatedTimestamp)
+		switch {
+		case !end.After(now.Add(b.Margin)):
+			stale[hour] = fmt.Sprintf("expires %s", end.Format(time.RFC3339))
+		case hour == now.Hour() && updated.Before(hourStart(now)):
+			stale[hour] = fmt.Sprintf("generated %s", updated.Format(time.RFC3339))
+		}
+	}
+
+	return stale, nil
+}
+
+// Run rebuilds every file Check reports. The current hour is generated with fresh articles as
+// usual. The other hours reuse the cached articles of that hour's last run, or of the closest run
+// when it failed, with the timestamps of that run. Hours that can't be built are reported in the
+// returned error, after the rest were.
+func (b *Backfill) Run(ctx context.Context) ([]Fill, error) {
+	stale, err := b.Check(ctx)
+	if err != nil || len(stale) == 0 {
+		return nil, err
+	}
+
+	now := b.Options.LocalNow()
+	var fills []Fill
+
+	current, currentStale := stale[now.Hour()]
+	delete(stale, now.Hour())
+	if currentStale {
+		fill, err := b.generate(ctx, now.Hour(), current)
+		if err != nil {
+			return nil, err
+		}
+		fills = append(fills, fill)
+	}
+
+	slots, err := b.slots(ctx, now)
+	if err != nil {
+		return fills, err
+	}
+
+	// Without any recent slot, generating the current hour leaves one to build the others from.
+	if len(slots) == 0 && len(stale) > 0 && !currentStale {
+		fill, err := b.generate(ctx, now.Hour(), "no recent articles to rebuild from")
+		if err != nil {
+			return fills, err
+		}
+		fills = append(fills, fill)
+
+		if slots, err = b.slots(ctx, now); err != nil {
+			return fills, err
+		}
+	}
+
+	hours := make([]int, 0, len(stale))
+	for hour := range stale {
+		hours = append(hours, hour)
+	}
+	sort.Ints(hours)
+
+	var errs []error
+	for _, hour := range hours {
+		slot, ok := closest(slots, lastOccurrence(now, hour))
+		if !ok {
+			errs = append(errs, fmt.Errorf("hour %02d: %w", hour, ErrNoArticles))
+			continue
+		}
+
+		result, err := generator.Replay(ctx, b.Options, slot, hour)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("hour %02d: %w", hour, err))
+			continue
+		}
+
+		fills = append(fills, Fill{Hour: hour, Reason: stale[hour], Slot: slot, Result: result})
+	}
+
+	return fills, errors.Join(errs...)
+}
+
+// generate builds the current hour's file with fresh articles.
+func (b *Backfill) generate(ctx context.Context, hour int, reason string) (Fill, error) {
+	result, err := generator.Generate(ctx, b.Options)
+	if err != nil {
+		return Fill{}, fmt.Errorf("hour %02d: %w", hour, err)
+	}
+
+	log.Printf("Generated %s with %d articles\n", result.Location, result.NumberOfArticles)
+	return Fill{Hour: hour, Reason: reason, Result: result}, nil
+}
+
+// slots returns the generation times of the cached slots whose file would still be valid beyond
+// Margin, oldest first.
+func (b *Backfill) slots(ctx context.Context, now time.Time) ([]time.Time, error) {
+	file, err := b.Options.CacheStore.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var slots []time.Time
+	for _, slot := range file.Slots {
+		if slot.GeneratedAt.After(now) || !slot.GeneratedAt.Add(generator.Lifetime).After(now.Add(b.Margin)) {
+			continue
+		}
+		slots = append(slots, slot.GeneratedAt)
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].Before(slots[j])
+	})
+	return slots, nil
+}
+
+// closest picks the slot to build the file for the hour starting at start from: the one generated
+// in that hour, otherwise the last one before it ended, otherwise the first one after.
+func closest(slots []time.Time, start time.Time) (time.Time, bool) {
+	if len(slots) == 0 {
+		return time.Time{}, false
+	}
+
+	end := start.Add(time.Hour)
+	best := slots[0]
+	for _, slot := range slots {
+		if slot.Before(end) {
+			best = slot
+		}
+	}
+	return best, true
+}
+
+// hourStart truncates t to the start of its hour in t's location.
+func hourStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+}
+
+// lastOccurrence is the start of the latest hour of the day numbered hour that began by now.
+func lastOccurrence(now time.Time, hour int) time.Time {
+	start := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if start.After(now) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
@@ -0,0 +1,128 @@
+package backfill
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/signing"
+	"WiiNewsPR/sink"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+func testOptions(cacheDir string, at time.Time, title string) generator.Options {
+	// The titles only differ in the hour, so a shared lead-in would make their bodies the same
+	// story.
+	body := title
+	return generator.Options{
+		CacheDir: cacheDir,
+		Signer:   signing.Unsigned{},
+		Source:   &newstest.Source{Articles: []news.Article{{Title: title, Content: &body, Topic: news.NationalNews}}},
+		Clock:    generator.FixedClock(at),
+	}
+}
+
+// generate runs the generator at, writing the file to out.
+func generate(t *testing.T, out sink.Sink, cacheDir string, at time.Time, title string) generator.Result {
+	t.Helper()
+
+	opts := testOptions(cacheDir, at, title)
+	opts.Output = out
+
+	result, err := generator.Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBackfill(t *testing.T) {
+	cacheDir := t.TempDir()
+	out := sink.NewDirSink(t.TempDir())
+
+	// Yesterday's file for hour 10 is about to expire, and the run at 16:30 failed.
+	generate(t, out, t.TempDir(), testTime.Add(-32*time.Hour), "Noticia de ayer")
+	generate(t, out, cacheDir, testTime.Add(-3*time.Hour), "Noticia de las 15")
+	generate(t, out, cacheDir, testTime.Add(-time.Hour), "Noticia de las 17")
+
+	b := &Backfill{Options: testOptions(cacheDir, testTime, "Noticia de las 18"), Sink: out}
+	stale, err := b.Check(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stale) != 22 || stale[16] != "missing" || !strings.HasPrefix(stale[10], "expires") {
+		t.Fatalf("stale = %v", stale)
+	}
+
+	fills, err := b.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 22 {
+		t.Fatalf("%d fills, want 22", len(fills))
+	}
+
+	byHour := map[int]Fill{}
+	for _, fill := range fills {
+		byHour[fill.Hour] = fill
+	}
+
+	// The current hour gets fresh articles, and the failed hour the ones of the run before it.
+	if fill := byHour[18]; !fill.Slot.IsZero() || fill.Result.NumberOfArticles != 1 {
+		t.Errorf("hour 18 = %s", fill)
+	}
+	if fill := byHour[16]; !fill.Slot.Equal(testTime.Add(-3 * time.Hour)) {
+		t.Errorf("hour 16 = %s", fill)
+	}
+	// Hours from before the first cached run use the first one.
+	if fill := byHour[10]; !fill.Slot.Equal(testTime.Add(-3 * time.Hour)) {
+		t.Errorf("hour 10 = %s", fill)
+	}
+
+	data, err := out.Read(context.Background(), byHour[16].Result.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := generator.DecodeFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Articles) != 1 || parsed.Articles[0].Title != "Noticia de las 15" {
+		t.Errorf("hour 16 articles = %+v", parsed.Articles)
+	}
+
+	if stale, err = b.Check(context.Background()); err != nil || len(stale) != 0 {
+		t.Errorf("stale after backfill = %v, %v", stale, err)
+	}
+}
+
+func TestBackfillWithoutCache(t *testing.T) {
+	out := sink.NewDirSink(t.TempDir())
+	generate(t, out, t.TempDir(), testTime, "Noticia de las 18")
+
+	// The cache is gone, so the current hour is generated again to build the others from.
+	b := &Backfill{Options: testOptions(t.TempDir(), testTime, "Noticia nueva"), Sink: out}
+	fills, err := b.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fills) != 24 || fills[0].Hour != 18 || !fills[0].Slot.IsZero() {
+		t.Fatalf("fills = %v", fills)
+	}
+}
+
+func TestBackfillNoArticles(t *testing.T) {
+	out := sink.NewDirSink(t.TempDir())
+
+	b := &Backfill{Options: testOptions(t.TempDir(), testTime, "Noticia"), Sink: out}
+	b.Options.Source = &newstest.Source{Err: errors.New("feed down")}
+
+	if _, err := b.Run(context.Background()); err == nil {
+		t.Fatal("backfill succeeded without any articles")
+	}
+}
@@ -0,0 +1,281 @@
+// Package cache keeps track of the articles written in previous hours. The News Channel needs a
+// timestamp entry for every article of the day, not only the current hour's, so each run records
+// what it wrote.
+package cache
+
+import (
+	"WiiNewsPR/news"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Version is the current schema version of the cache file.
+const Version = 2
+
+// DefaultRetention is how far back slots are used. The channel shows the articles of the last day.
+const DefaultRetention = 24 * time.Hour
+
+// slotKeyLayout formats the UTC hour a slot was generated in.
+const slotKeyLayout = "2006-01-02T15"
+
+var (
+	// ErrCorrupt is returned when the cache file can't be decoded at all.
+	ErrCorrupt = errors.New("corrupt cache file")
+	// ErrUnsupportedVersion is returned for files written by a newer generator.
+	ErrUnsupportedVersion = errors.New("unsupported cache version")
+)
+
+// Entry is an article written in a previous hour.
+type Entry struct {
+	ID        uint32     `json:"id"`
+	Timestamp uint32     `json:"timestamp"`
+	Topic     news.Topic `json:"topic"`
+	Title     string     `json:"title"`
+	// Fingerprint summarises the body, so rewritten versions of the story are recognised later.
+	Fingerprint news.Fingerprint `json:"fingerprint,omitempty"`
+	// ContentHash identifies the body, so a story whose body changed is re-emitted as an update.
+	ContentHash string `json:"contentHash,omitempty"`
+	// Published is the timestamp the story was first written with, if this entry is an update.
+	Published uint32 `json:"published,omitempty"`
+	// Article is the article as it was written, so the file for the hour can be built again. Its
+	// picture is stored separately, under the name in Image.
+	Article *news.Article `json:"article,omitempty"`
+	Image   string        `json:"image,omitempty"`
+}
+
+// PublishedAt is the timestamp the story was first written with.
+func (e Entry) PublishedAt() uint32 {
+	if e.Published != 0 {
+		return e.Published
+	}
+	return e.Timestamp
+}
+
+// SameStory reports whether other is a version of the same story. An update keeps the ID and
+// publication time of the story it replaces.
+func (e Entry) SameStory(other Entry) bool {
+	return e.ID == other.ID && e.PublishedAt() == other.PublishedAt()
+}
+
+// Slot holds the articles written for one hour.
+type Slot struct {
+	GeneratedAt time.Time `json:"generatedAt"`
+	Articles    []Entry   `json:"articles"`
+	// Message is the urgent message the file carried, if any.
+	Message *news.Message `json:"message,omitempty"`
+}
+
+// File is the whole cache, with one slot per generated hour.
+type File struct {
+	Version int             `json:"version"`
+	Slots   map[string]Slot `json:"slots"`
+
+	// decodedVersion is the version the file had on disk, before migrations.
+	decodedVersion int
+}
+
+// Corrupt is a slot that failed to decode and was left out of the file.
+type Corrupt struct {
+	Key  string
+	Data []byte
+	Err  error
+}
+
+// NewFile returns an empty cache at the current version.
+func NewFile() *File {
+	return &File{
+		Version: Version,
+		Slots:   map[string]Slot{},
+	}
+}
+
+// slotKey is the key of the slot for the hour t falls in.
+func slotKey(t time.Time) string {
+	return t.UTC().Format(slotKeyLayout)
+}
+
+// Slot returns the articles written in the hour t falls in.
+func (f *File) Slot(t time.Time) (Slot, bool) {
+	slot, ok := f.Slots[slotKey(t)]
+	return slot, ok
+}
+
+// SetSlot replaces the articles written in the hour t falls in, recording t as its generation time.
+func (f *File) SetSlot(t time.Time, slot Slot) {
+	slot.GeneratedAt = t.UTC()
+	f.Slots[slotKey(t)] = slot
+}
+
+// Recent returns the slots generated within retention before now, oldest first. The slot for the
+// current hour is left out, as it is about to be regenerated.
+func (f *File) Recent(now time.Time, retention time.Duration) []Slot {
+	current := slotKey(now)
+	cutoff := now.Add(-retention)
+
+	var slots []Slot
+	for key, slot := range f.Slots {
+		if key == current || !slot.GeneratedAt.After(cutoff) || slot.GeneratedAt.After(now) {
+			continue
+		}
+		slots = append(slots, slot)
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		return slots[i].GeneratedAt.Before(slots[j].GeneratedAt)
+	})
+	return slots
+}
+
+// Prune removes the slots generated more than retention before now.
+func (f *File) Prune(now time.Time, retention time.Duration) {
+	cutoff := now.Add(-retention)
+	for key, slot := range f.Slots {
+		if !slot.GeneratedAt.After(cutoff) {
+			delete(f.Slots, key)
+		}
+	}
+}
+
+// wiiEpoch is the Unix time of 2000-01-01, which entry timestamps count minutes from.
+const wiiEpoch = 946684800
+
+// generatedAtFromEntries estimates when a slot without a generation time was written, from the
+// newest timestamp of its entries.
+func generatedAtFromEntries(entries []Entry) (time.Time, bool) {
+	var newest uint32
+	for _, entry := range entries {
+		newest = max(newest, entry.Timestamp)
+	}
+
+	if newest == 0 {
+		return time.Time{}, false
+	}
+
+	return time.Unix(wiiEpoch+int64(newest)*60, 0).UTC(), true
+}
+
+// rawFile is a cache file whose slots have not been decoded yet.
+type rawFile struct {
+	Version int                        `json:"version"`
+	Slots   map[string]json.RawMessage `json:"slots"`
+}
+
+// migrations upgrade the raw slots of a file from the version they are keyed by to the next one.
+var migrations = map[int]func(*rawFile) error{
+	1: migrateHourSlots,
+}
+
+// migrateHourSlots converts version 1 slots, keyed by hour of the day, into slots keyed by the
+// hour they were generated in. Slots that can't be converted keep their key and end up quarantined.
+func migrateHourSlots(raw *rawFile) error {
+	slots := map[string]json.RawMessage{}
+	for key, data := range raw.Slots {
+		var slot Slot
+		if err := json.Unmarshal(data, &slot); err != nil {
+			slots[key] = data
+			continue
+		}
+
+		generatedAt, ok := generatedAtFromEntries(slot.Articles)
+		if !ok {
+			// An empty slot adds nothing to the cache.
+			continue
+		}
+
+		slot.GeneratedAt = generatedAt
+		migrated, err := json.Marshal(slot)
+		if err != nil {
+			return err
+		}
+		slots[slotKey(generatedAt)] = migrated
+	}
+
+	raw.Slots = slots
+	return nil
+}
+
+// Decode parses a cache file, migrating it to the current version. Slots that fail to decode
+// are returned separately so the rest of the cache stays usable.
+func Decode(data []byte) (*File, []Corrupt, error) {
+	var raw rawFile
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+
+	if raw.Version < 1 {
+		return nil, nil, fmt.Errorf("%w: missing version", ErrCorrupt)
+	}
+
+	if raw.Version > Version {
+		return nil, nil, fmt.Errorf("%w: %d is newer than %d", ErrUnsupportedVersion, raw.Version, Version)
+	}
+
+	decodedVersion := raw.Version
+	for raw.Version < Version {
+		migrate, ok := migrations[raw.Version]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: no migration from version %d", ErrUnsupportedVersion, raw.Version)
+		}
+
+		if err := migrate(&raw); err != nil {
+			return nil, nil, fmt.Errorf("failed to migrate cache from version %d: %w", raw.Version, err)
+		}
+		raw.Version++
+	}
+
+	file := NewFile()
+	file.decodedVersion = decodedVersion
+
+	var corrupt []Corrupt
+	for key, data := range raw.Slots {
+		slot, err := decodeSlot(key, data)
+		if err != nil {
+			corrupt = append(corrupt, Corrupt{Key: key, Data: data, Err: err})
+			continue
+		}
+		file.Slots[key] = slot
+	}
+
+	return file, corrupt, nil
+}
+
+func decodeSlot(key string, data []byte) (Slot, error) {
+	if _, err := time.Parse(slotKeyLayout, key); err != nil {
+		return Slot{}, fmt.Errorf("invalid slot key %q", key)
+	}
+
+	var slot Slot
+	if err := json.Unmarshal(data, &slot); err != nil {
+		return Slot{}, err
+	}
+
+	if err := validateEntries(slot.Articles); err != nil {
+		return Slot{}, err
+	}
+
+	if slot.GeneratedAt.IsZero() {
+		return Slot{}, errors.New("missing generation time")
+	}
+
+	return slot, nil
+}
+
+// validateEntries rejects entries the generator can't use, such as unknown topics.
+func validateEntries(entries []Entry) error {
+	for _, entry := range entries {
+		if entry.Topic < news.NationalNews || entry.Topic > news.Technology {
+			return fmt.Errorf("entry %d has unknown topic %d", entry.ID, entry.Topic)
+		}
+	}
+
+	return nil
+}
+
+// Encode serialises the cache at the current version.
+func Encode(f *File) ([]byte, error) {
+	f.Version = Version
+	return json.Marshal(f)
+}
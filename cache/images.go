@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"WiiNewsPR/news"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+)
+
+// ImageDir holds the pictures of cached articles next to the cache file. They are kept out of the
+// file, which is read and written on every run, and only read back to build an hour again.
+const ImageDir = "images"
+
+// ImageName is the name a picture is stored under: the SHA-256 of its contents, so the same
+// picture is only stored once however many hours carry it.
+func ImageName(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Images returns the names of the pictures the slots refer to.
+func (f *File) Images() map[string]bool {
+	names := map[string]bool{}
+	for _, slot := range f.Slots {
+		for _, entry := range slot.Articles {
+			if entry.Image != "" {
+				names[entry.Image] = true
+			}
+		}
+	}
+	return names
+}
+
+// StoreImages moves the pictures of the cached articles out of file and into the store, leaving
+// their names in Entry.Image. Pictures named in stored are already in the store and aren't
+// written again. Articles are copied rather than changed, as they may still be in use.
+func StoreImages(ctx context.Context, store Store, file *File, stored map[string]bool) error {
+	for key, slot := range file.Slots {
+		for i, entry := range slot.Articles {
+			if entry.Article == nil || entry.Article.Thumbnail == nil || len(entry.Article.Thumbnail.Image) == 0 {
+				continue
+			}
+
+			name := ImageName(entry.Article.Thumbnail.Image)
+			if !stored[name] {
+				if err := store.SaveImage(ctx, name, entry.Article.Thumbnail.Image); err != nil {
+					return fmt.Errorf("failed to store picture of %q: %w", entry.Title, err)
+				}
+				stored[name] = true
+			}
+
+			article := *entry.Article
+			thumbnail := *article.Thumbnail
+			thumbnail.Image = nil
+			article.Thumbnail = &thumbnail
+
+			slot.Articles[i].Article = &article
+			slot.Articles[i].Image = name
+		}
+		file.Slots[key] = slot
+	}
+
+	return nil
+}
+
+// LoadImages returns the articles of slot with their pictures read back from the store.
+func LoadImages(ctx context.Context, store Store, slot Slot) ([]news.Article, error) {
+	articles := make([]news.Article, 0, len(slot.Articles))
+	for _, entry := range slot.Articles {
+		article := *entry.Article
+		if entry.Image != "" && article.Thumbnail != nil {
+			data, err := store.LoadImage(ctx, entry.Image)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load picture of %q: %w", entry.Title, err)
+			}
+
+			thumbnail := *article.Thumbnail
+			thumbnail.Image = data
+			article.Thumbnail = &thumbnail
+		}
+		articles = append(articles, article)
+	}
+
+	return articles, nil
+}
+
+// RemoveUnusedImages deletes the pictures among previous that file no longer refers to. It runs
+// once the file is saved, so a failed save never loses a picture. A picture that can't be deleted
+// only takes up space, so failures are logged.
+func RemoveUnusedImages(ctx context.Context, store Store, file *File, previous map[string]bool) {
+	used := file.Images()
+	for name := range previous {
+		if used[name] {
+			continue
+		}
+
+		if err := store.DeleteImage(ctx, name); err != nil {
+			log.Printf("Warning: Failed to remove cached picture %s: %v\n", name, err)
+		}
+	}
+}
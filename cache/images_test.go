@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/objstore/objstoretest"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestImages(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store := NewDirStore(dir)
+
+	picture := []byte{0xFF, 0xD8, 0xFF, 0xE0}
+	thumbnail := &news.Thumbnail{Image: picture, Caption: "Río Piedras"}
+	article := news.Article{Title: "Inundaciones en el área metro", Topic: news.Science, Thumbnail: thumbnail}
+
+	file := NewFile()
+	file.SetSlot(testTime, Slot{Articles: []Entry{{ID: 1, Title: article.Title, Article: &article}}})
+	if err := StoreImages(ctx, store, file, map[string]bool{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+
+	// The picture is stored once, outside the cache file, and the article it came from is left as it was.
+	name := ImageName(picture)
+	if data, err := os.ReadFile(filepath.Join(dir, ImageDir, name)); err != nil || !bytes.Equal(data, picture) {
+		t.Fatalf("stored picture = %x, %v", data, err)
+	}
+	if !bytes.Equal(thumbnail.Image, picture) {
+		t.Error("StoreImages changed the article")
+	}
+
+	loaded, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slot, _ := loaded.Slot(testTime)
+	if entry := slot.Articles[0]; entry.Image != name || len(entry.Article.Thumbnail.Image) != 0 || entry.Article.Thumbnail.Caption != "Río Piedras" {
+		t.Fatalf("cached entry = %+v, thumbnail = %+v", entry, entry.Article.Thumbnail)
+	}
+
+	articles, err := LoadImages(ctx, store, slot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(articles[0].Thumbnail.Image, picture) {
+		t.Errorf("loaded picture = %x", articles[0].Thumbnail.Image)
+	}
+
+	// Once the slot is pruned, nothing refers to the picture any more.
+	previous := loaded.Images()
+	loaded.Prune(testTime.Add(48*time.Hour), DefaultRetention)
+	RemoveUnusedImages(ctx, store, loaded, previous)
+	if _, err = os.Stat(filepath.Join(dir, ImageDir, name)); !os.IsNotExist(err) {
+		t.Errorf("unused picture was kept: %v", err)
+	}
+}
+
+func TestObjectStoreImages(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewObjectStore(server.Client(), "cache/")
+
+	if err := store.SaveImage(ctx, "abc", []byte{1, 2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if data, ok := server.Object("cache/" + ImageDir + "/abc"); !ok || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Fatalf("object = %v, %v", data, ok)
+	}
+
+	if data, err := store.LoadImage(ctx, "abc"); err != nil || !bytes.Equal(data, []byte{1, 2, 3}) {
+		t.Errorf("LoadImage = %v, %v", data, err)
+	}
+
+	if err := store.DeleteImage(ctx, "abc"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := server.Keys(); len(keys) != 0 {
+		t.Errorf("keys = %v", keys)
+	}
+}
@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrLocked is returned while another generation holds the cache.
+	ErrLocked = errors.New("another generation is in progress")
+	// ErrConflict is returned when the cache was changed by someone else between Load and Save.
+	ErrConflict = errors.New("cache was modified by another generation")
+	// ErrLockLost is returned when another generation took over the lock while it was held.
+	ErrLockLost = errors.New("cache lock was lost")
+)
+
+const (
+	// LockFileName is the lock taken next to the cache file.
+	LockFileName = "cache.lock"
+	// LockTTL is how long a lock is honoured where the holder can't be checked, such as a lock
+	// object left behind by a crashed serverless run.
+	LockTTL = 10 * time.Minute
+	// lockPollInterval is how often Acquire retries while waiting.
+	lockPollInterval = 250 * time.Millisecond
+)
+
+// Unlock releases a lock taken with Store.Lock.
+type Unlock func() error
+
+// Acquire locks store, retrying for up to timeout while another generation holds it. A zero
+// timeout fails straight away with ErrLocked.
+func Acquire(ctx context.Context, store Store, timeout time.Duration) (Unlock, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		unlock, err := store.Lock(ctx)
+		if !errors.Is(err, ErrLocked) {
+			return unlock, err
+		}
+
+		if !time.Now().Before(deadline) {
+			if timeout > 0 {
+				return nil, fmt.Errorf("%w (waited %s)", err, timeout)
+			}
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(min(lockPollInterval, time.Until(deadline))):
+		}
+	}
+}
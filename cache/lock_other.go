@@ -0,0 +1,40 @@
+//go:build !unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+// lockFile creates path exclusively. Without advisory locks a crashed run leaves the file behind,
+// so a lock older than LockTTL is taken over.
+func lockFile(path string) (Unlock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+	if errors.Is(err, os.ErrExist) {
+		info, statErr := os.Stat(path)
+		if statErr != nil || time.Since(info.ModTime()) < LockTTL {
+			return nil, ErrLocked
+		}
+
+		if err = os.Remove(path); err != nil {
+			return nil, ErrLocked
+		}
+		f, err = os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrLocked
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = f.Close(); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return os.Remove(path)
+	}, nil
+}
@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"WiiNewsPR/objstore/objstoretest"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDirStoreLock(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	unlock, err := NewDirStore(dir).Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewDirStore(dir).Lock(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second lock: err = %v, want ErrLocked", err)
+	}
+
+	if err = unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err = NewDirStore(dir).Lock(ctx)
+	if err != nil {
+		t.Fatalf("lock after release: %v", err)
+	}
+	unlock()
+}
+
+func TestAcquireWaits(t *testing.T) {
+	ctx := context.Background()
+	store := NewDirStore(t.TempDir())
+
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = Acquire(ctx, store, 0); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Acquire without timeout: err = %v, want ErrLocked", err)
+	}
+
+	if _, err = Acquire(ctx, store, 100*time.Millisecond); !errors.Is(err, ErrLocked) || !strings.Contains(err.Error(), "waited") {
+		t.Fatalf("Acquire timing out: err = %v", err)
+	}
+
+	time.AfterFunc(100*time.Millisecond, func() { unlock() })
+
+	second, err := Acquire(ctx, store, 5*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire after release: %v", err)
+	}
+	second()
+}
+
+func TestObjectStoreLock(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	unlock, err := NewObjectStore(server.Client(), "cache/").Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = NewObjectStore(server.Client(), "cache/").Lock(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second lock: err = %v, want ErrLocked", err)
+	}
+
+	if err = unlock(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Object("cache/" + LockFileName); ok {
+		t.Error("lock object was not removed")
+	}
+
+	// A lock left behind by a crashed run is taken over once it expires.
+	server.SetObject("cache/"+LockFileName, []byte(`{"expires":"2020-01-01T00:00:00Z"}`))
+	unlock, err = NewObjectStore(server.Client(), "cache/").Lock(ctx)
+	if err != nil {
+		t.Fatalf("taking over an expired lock: %v", err)
+	}
+	unlock()
+}
+
+func TestObjectStoreSaveConflict(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	first := NewObjectStore(server.Client(), "")
+	second := NewObjectStore(server.Client(), "")
+
+	firstFile, err := first.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondFile, err := second.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = second.Save(ctx, secondFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both runs saw an empty bucket; the one that saves last must not overwrite the other.
+	if err = first.Save(ctx, firstFile); !errors.Is(err, ErrConflict) {
+		t.Fatalf("stale save: err = %v, want ErrConflict", err)
+	}
+
+	// Saving again after a save of its own is fine.
+	if err = second.Save(ctx, secondFile); err != nil {
+		t.Errorf("second save: %v", err)
+	}
+}
+
+func TestObjectStoreLockRenewed(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewObjectStore(server.Client(), "")
+	store.ttl = 300 * time.Millisecond
+
+	unlock, err := store.Lock(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Well past the first lease, the lock is still held.
+	time.Sleep(3 * store.ttl)
+	if _, err = NewObjectStore(server.Client(), "").Lock(ctx); !errors.Is(err, ErrLocked) {
+		t.Fatalf("lock after the first lease: err = %v, want ErrLocked", err)
+	}
+
+	file, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if err = unlock(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Once another run takes the lock over, the store can't save any more.
+	if unlock, err = store.Lock(ctx); err != nil {
+		t.Fatal(err)
+	}
+	server.SetObject(LockFileName, []byte(`{"expires":"2099-01-01T00:00:00Z"}`))
+	time.Sleep(store.ttl)
+
+	if err = store.Save(ctx, file); !errors.Is(err, ErrLockLost) {
+		t.Errorf("save after takeover: err = %v, want ErrLockLost", err)
+	}
+	if err = unlock(); err == nil {
+		t.Error("releasing a lock that was taken over succeeded")
+	}
+}
@@ -0,0 +1,32 @@
+//go:build unix
+
+package cache
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on path. The kernel drops it if the process dies, so a crashed
+// run never leaves the cache locked.
+func lockFile(path string) (Unlock, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0666)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrLocked
+		}
+		return nil, err
+	}
+
+	return func() error {
+		// Closing the file releases the lock. The file itself is left in place, as removing it
+		// could race with another run that has just opened it.
+		return f.Close()
+	}, nil
+}
@@ -0,0 +1,242 @@
+package cache
+
+import (
+	"WiiNewsPR/objstore"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// ObjectStore keeps the cache file in S3-compatible object storage, under a key prefix. Writes are
+// conditional on the entity tag seen by Load, so a run that lost track of the lock can't overwrite
+// a newer cache. It can be shared by goroutines, but only the one holding the lock should Load and
+// Save.
+type ObjectStore struct {
+	client *objstore.Client
+	prefix string
+	// ttl is how long a lease lasts. It is renewed a few times within it while the lock is held.
+	ttl time.Duration
+
+	mu sync.Mutex
+	// etag is the entity tag of the cache object when it was loaded or last saved. Empty means
+	// it didn't exist.
+	etag string
+	// lockErr is set when the lease of the held lock couldn't be renewed because another run took
+	// it over. Save fails with it.
+	lockErr error
+}
+
+// NewObjectStore stores the cache at prefix + FileName. A non-empty prefix should end in a slash.
+func NewObjectStore(client *objstore.Client, prefix string) *ObjectStore {
+	return &ObjectStore{client: client, prefix: prefix, ttl: LockTTL}
+}
+
+// lease is the content of the lock object.
+type lease struct {
+	Expires time.Time `json:"expires"`
+}
+
+// Lock creates the lock object, which only succeeds if it doesn't exist. A lock left behind by a
+// run that crashed is taken over once it expires after LockTTL. While the lock is held its lease is
+// renewed, so a slow run doesn't lose it halfway through.
+func (s *ObjectStore) Lock(ctx context.Context) (Unlock, error) {
+	key := s.prefix + LockFileName
+	data, err := s.lease()
+	if err != nil {
+		return nil, err
+	}
+
+	etag, err := s.client.Put(ctx, key, data, objstore.PutOptions{ContentType: "application/json", IfNoneMatch: "*"})
+	if errors.Is(err, objstore.ErrPreconditionFailed) {
+		etag, err = s.takeOverExpired(ctx, key, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.lockErr = nil
+	s.mu.Unlock()
+
+	stop := make(chan struct{})
+	renewed := make(chan string)
+	go func() {
+		renewed <- s.renew(key, etag, stop)
+	}()
+
+	return func() error {
+		close(stop)
+		etag := <-renewed
+
+		// Only remove the lock if it is still ours.
+		current, err := s.client.Get(context.Background(), key)
+		if errors.Is(err, objstore.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if current.ETag != etag {
+			return fmt.Errorf("cache lock %s was taken over before it was released", key)
+		}
+		return s.client.Delete(context.Background(), key)
+	}, nil
+}
+
+// lease returns the content of a lock object that expires after the store's TTL.
+func (s *ObjectStore) lease() ([]byte, error) {
+	return json.Marshal(lease{Expires: time.Now().Add(s.ttl).UTC()})
+}
+
+// renew extends the lease of the lock object with entity tag etag until stop is closed, and
+// returns the entity tag of the last lease. If another run took the lock over, renewing stops and
+// Save fails from then on. Other errors are retried at the next renewal, while the lease lasts.
+func (s *ObjectStore) renew(key, etag string, stop <-chan struct{}) string {
+	ticker := time.NewTicker(s.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return etag
+		case <-ticker.C:
+		}
+
+		data, err := s.lease()
+		if err != nil {
+			log.Printf("Warning: Failed to renew the cache lock: %v\n", err)
+			continue
+		}
+
+		next, err := s.client.Put(context.Background(), key, data, objstore.PutOptions{ContentType: "application/json", IfMatch: etag})
+		if errors.Is(err, objstore.ErrPreconditionFailed) {
+			s.mu.Lock()
+			s.lockErr = fmt.Errorf("%w: %s was taken over by another generation", ErrLockLost, key)
+			s.mu.Unlock()
+
+			<-stop
+			return etag
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to renew the cache lock: %v\n", err)
+			continue
+		}
+		etag = next
+	}
+}
+
+// takeOverExpired replaces an existing lock object if its lease has run out.
+func (s *ObjectStore) takeOverExpired(ctx context.Context, key string, data []byte) (string, error) {
+	current, err := s.client.Get(ctx, key)
+	if errors.Is(err, objstore.ErrNotFound) {
+		// Released in the meantime. Let the caller retry from the start.
+		return "", ErrLocked
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var held lease
+	if err = json.Unmarshal(current.Data, &held); err == nil && time.Now().Before(held.Expires) {
+		return "", ErrLocked
+	}
+
+	etag, err := s.client.Put(ctx, key, data, objstore.PutOptions{ContentType: "application/json", IfMatch: current.ETag})
+	if errors.Is(err, objstore.ErrPreconditionFailed) {
+		return "", ErrLocked
+	}
+	return etag, err
+}
+
+// Load reads the cache object. Corrupt data is copied under the quarantine prefix.
+func (s *ObjectStore) Load(ctx context.Context) (*File, error) {
+	key := s.prefix + FileName
+	obj, err := s.client.Get(ctx, key)
+	if errors.Is(err, objstore.ErrNotFound) {
+		s.setETag("")
+		return NewFile(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.setETag(obj.ETag)
+
+	file, changed, err := decode(s.client.Bucket+"/"+key, obj.Data, func(name string, data []byte) error {
+		_, err := s.client.Put(ctx, s.prefix+QuarantineDir+"/"+quarantineName(name), data, objstore.PutOptions{ContentType: "application/json"})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if changed {
+		if err = s.Save(ctx, file); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// Save replaces the cache object if it hasn't changed since Load, and fails with ErrConflict
+// otherwise. It fails with ErrLockLost if the lock was taken over. A single PUT is atomic, so
+// readers never see a partial file.
+func (s *ObjectStore) Save(ctx context.Context, file *File) error {
+	s.mu.Lock()
+	current, lockErr := s.etag, s.lockErr
+	s.mu.Unlock()
+	if lockErr != nil {
+		return lockErr
+	}
+
+	data, err := Encode(file)
+	if err != nil {
+		return err
+	}
+
+	opts := objstore.PutOptions{ContentType: "application/json", IfMatch: current}
+	if current == "" {
+		opts.IfNoneMatch = "*"
+	}
+
+	etag, err := s.client.Put(ctx, s.prefix+FileName, data, opts)
+	if errors.Is(err, objstore.ErrPreconditionFailed) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	s.setETag(etag)
+	return nil
+}
+
+func (s *ObjectStore) setETag(etag string) {
+	s.mu.Lock()
+	s.etag = etag
+	s.mu.Unlock()
+}
+
+// SaveImage uploads a picture under the images prefix.
+func (s *ObjectStore) SaveImage(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.Put(ctx, s.prefix+ImageDir+"/"+name, data, objstore.PutOptions{ContentType: "application/octet-stream"})
+	return err
+}
+
+// LoadImage downloads a picture from the images prefix.
+func (s *ObjectStore) LoadImage(ctx context.Context, name string) ([]byte, error) {
+	obj, err := s.client.Get(ctx, s.prefix+ImageDir+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Data, nil
+}
+
+// DeleteImage removes a picture from the images prefix.
+func (s *ObjectStore) DeleteImage(ctx context.Context, name string) error {
+	return s.client.Delete(ctx, s.prefix+ImageDir+"/"+name)
+}
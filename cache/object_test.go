@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/objstore/objstoretest"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestObjectStoreRoundTrip(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	store := NewObjectStore(server.Client(), "cache/")
+
+	file, err := store.Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Slots) != 0 {
+		t.Fatalf("new cache has %d slots", len(file.Slots))
+	}
+
+	file.SetSlot(testTime, Slot{Articles: []Entry{{ID: 1, Timestamp: 100, Topic: news.Sports, Title: "Título"}}})
+	if err = store.Save(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := server.Keys(); len(keys) != 1 || keys[0] != "cache/"+FileName {
+		t.Fatalf("keys = %v", keys)
+	}
+
+	loaded, err := NewObjectStore(server.Client(), "cache/").Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if slot, ok := loaded.Slot(testTime); !ok || slot.Articles[0].Title != "Título" {
+		t.Errorf("slot = %+v, %v", slot, ok)
+	}
+}
+
+func TestObjectStoreQuarantinesCorruptFile(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	server.SetObject(FileName, []byte(`{"version":2,"slots":{"1":`))
+
+	file, err := NewObjectStore(server.Client(), "").Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Slots) != 0 {
+		t.Errorf("corrupt cache loaded %d slots", len(file.Slots))
+	}
+
+	// The corrupt file is kept under the quarantine prefix and replaced by an empty cache.
+	keys := server.Keys()
+	if len(keys) != 2 || keys[0] != FileName || !strings.HasPrefix(keys[1], QuarantineDir+"/cache.") {
+		t.Fatalf("keys = %v", keys)
+	}
+	if data, _ := server.Object(FileName); !strings.HasPrefix(string(data), `{"version":2,`) {
+		t.Errorf("cache object was not rewritten: %s", data)
+	}
+}
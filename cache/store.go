@@ -0,0 +1,259 @@
+package cache
+
+import (
+	"WiiNewsPR/objstore"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+const (
+	// FileName is the name of the cache file inside the cache directory.
+	FileName = "cache.json"
+	// QuarantineDir receives anything that could not be decoded, for later inspection.
+	QuarantineDir = "quarantine"
+)
+
+// legacyFile matches the per-hour files written before the cache was versioned.
+var legacyFile = regexp.MustCompile(`^cache_\d{1,2}\.news$`)
+
+// Store loads and saves the cache file. Load returns an empty file when there is no cache yet, and
+// quarantines corrupt data instead of failing the run. Lock is held from before Load until after
+// Save, so overlapping runs can't interleave; it fails with ErrLocked while another run holds it.
+// The pictures of cached articles are stored next to the file, by ImageName.
+type Store interface {
+	Lock(ctx context.Context) (Unlock, error)
+	Load(ctx context.Context) (*File, error)
+	Save(ctx context.Context, file *File) error
+	SaveImage(ctx context.Context, name string, data []byte) error
+	LoadImage(ctx context.Context, name string) ([]byte, error)
+	DeleteImage(ctx context.Context, name string) error
+}
+
+// Open returns the store for location: an s3://bucket/prefix URL or a local directory.
+func Open(location string) (Store, error) {
+	if objstore.IsURL(location) {
+		client, prefix, err := objstore.FromURL(location)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjectStore(client, prefix), nil
+	}
+
+	return NewDirStore(location), nil
+}
+
+// decode reads a stored cache file. Corrupt data is handed to quarantine and left out of the
+// result, which also reports whether the file needs to be saved again.
+func decode(location string, data []byte, quarantine func(name string, data []byte) error) (*File, bool, error) {
+	file, corrupt, err := Decode(data)
+	if errors.Is(err, ErrCorrupt) {
+		if err = quarantine("cache", data); err != nil {
+			return nil, false, err
+		}
+		log.Printf("Cache file %s is corrupt and was quarantined, starting with an empty cache\n", location)
+		return NewFile(), true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, c := range corrupt {
+		if err = quarantine("slot-"+c.Key, c.Data); err != nil {
+			return nil, false, err
+		}
+		log.Printf("Cache slot %s is corrupt and was quarantined: %v\n", c.Key, c.Err)
+	}
+
+	return file, len(corrupt) > 0 || file.decodedVersion != Version, nil
+}
+
+// quarantineName makes quarantined copies of the same data unique.
+func quarantineName(name string) string {
+	return fmt.Sprintf("%s.%d", name, time.Now().UnixNano())
+}
+
+// DirStore keeps the cache file in a local directory.
+type DirStore struct {
+	dir string
+}
+
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// Lock takes an advisory lock on the cache directory.
+func (s *DirStore) Lock(_ context.Context) (Unlock, error) {
+	if err := os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	return lockFile(filepath.Join(s.dir, LockFileName))
+}
+
+// Load reads the cache. Legacy per-hour files are migrated into the cache file, and corrupt data
+// is moved to the quarantine directory.
+func (s *DirStore) Load(ctx context.Context) (*File, error) {
+	file, changed, err := s.readFile()
+	if err != nil {
+		return nil, err
+	}
+
+	migrated, err := s.migrateLegacy(file)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed || len(migrated) > 0 {
+		if err = s.Save(ctx, file); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only remove the legacy files once their contents are safely in the cache file.
+	for _, path := range migrated {
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return file, nil
+}
+
+// readFile decodes the cache file, reporting whether anything had to be quarantined or migrated.
+func (s *DirStore) readFile() (*File, bool, error) {
+	path := filepath.Join(s.dir, FileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return NewFile(), false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return decode(path, data, s.quarantine)
+}
+
+// migrateLegacy moves cache_N.news files into file. It returns the files that can now be removed.
+func (s *DirStore) migrateLegacy(file *File) ([]string, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []string
+	for _, dirEntry := range dirEntries {
+		if !legacyFile.MatchString(dirEntry.Name()) {
+			continue
+		}
+
+		path := filepath.Join(s.dir, dirEntry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var entries []Entry
+		err = json.Unmarshal(data, &entries)
+		if err == nil {
+			err = validateEntries(entries)
+		}
+
+		if err != nil {
+			if err = s.quarantine("legacy-"+dirEntry.Name(), data); err != nil {
+				return nil, err
+			}
+			log.Printf("Legacy cache file %s is corrupt and was quarantined\n", path)
+		} else if generatedAt, ok := generatedAtFromEntries(entries); ok {
+			if _, exists := file.Slot(generatedAt); !exists {
+				file.SetSlot(generatedAt, Slot{Articles: entries})
+			}
+		}
+
+		migrated = append(migrated, path)
+	}
+
+	return migrated, nil
+}
+
+// Save atomically replaces the cache file: it is written to a temporary file first, then renamed.
+func (s *DirStore) Save(_ context.Context, file *File) error {
+	data, err := Encode(file)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(s.dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(s.dir, FileName), data)
+}
+
+// SaveImage writes a picture to the images directory.
+func (s *DirStore) SaveImage(_ context.Context, name string, data []byte) error {
+	dir := filepath.Join(s.dir, ImageDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filepath.Join(dir, name), data)
+}
+
+// LoadImage reads a picture from the images directory.
+func (s *DirStore) LoadImage(_ context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, ImageDir, name))
+}
+
+// DeleteImage removes a picture. A missing picture is not an error.
+func (s *DirStore) DeleteImage(_ context.Context, name string) error {
+	err := os.Remove(filepath.Join(s.dir, ImageDir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *DirStore) quarantine(name string, data []byte) error {
+	dir := filepath.Join(s.dir, QuarantineDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, quarantineName(name)), data, 0666)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	// Removing fails harmlessly once the rename has happened.
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err = temp.Sync(); err != nil {
+		temp.Close()
+		return err
+	}
+
+	if err = temp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temp.Name(), path)
+}
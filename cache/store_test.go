@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"WiiNewsPR/news"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testTime is the generation time of the entries with timestamp 12975510.
+var testTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(data), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func quarantined(t *testing.T, dir string) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(filepath.Join(dir, QuarantineDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewDirStore(dir)
+
+	file, err := store.Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Slots) != 0 {
+		t.Fatalf("new cache has %d slots", len(file.Slots))
+	}
+
+	slot := Slot{Articles: []Entry{{ID: 1, Timestamp: 100, Topic: news.Sports, Title: "Título"}}}
+	file.SetSlot(testTime, slot)
+	slot.GeneratedAt = testTime
+	if err = store.Save(context.Background(), file); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewDirStore(dir).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := loaded.Slot(testTime)
+	if !ok || !reflect.DeepEqual(got, slot) {
+		t.Errorf("slot = %+v, want %+v", got, slot)
+	}
+
+	// Nothing but the cache file should be left behind by the atomic write.
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 || entries[0].Name() != FileName {
+		t.Errorf("unexpected files in cache directory: %v", entries)
+	}
+}
+
+func TestStoreMigratesLegacyFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "cache_3.news"), `[{"id":1,"timestamp":12975450,"topic":0,"title":"Uno"},{"id":2,"timestamp":12975450,"topic":2,"title":"Dos"}]`)
+	writeFile(t, filepath.Join(dir, "cache_4.news"), `[{"id":1,"timestamp":12975510,"topic":4,"title":"Tres"}]`)
+	writeFile(t, filepath.Join(dir, "cache_5.news"), `[{"id":1,"timestamp":`)
+
+	file, err := NewDirStore(dir).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The legacy files have no generation time, so it comes from the newest entry timestamp.
+	slots := file.Recent(testTime.Add(time.Hour), DefaultRetention)
+	if len(slots) != 2 || !slots[0].GeneratedAt.Equal(testTime.Add(-time.Hour)) || !slots[1].GeneratedAt.Equal(testTime) {
+		t.Fatalf("slots = %+v", slots)
+	}
+
+	if slot := slots[0]; len(slot.Articles) != 2 || slot.Articles[1].Title != "Dos" || slot.Articles[1].Topic != news.Sports {
+		t.Errorf("first slot = %+v", slot)
+	}
+
+	for _, name := range []string{"cache_3.news", "cache_4.news", "cache_5.news"} {
+		if _, err = os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("legacy file %s was not removed", name)
+		}
+	}
+
+	if names := quarantined(t, dir); len(names) != 1 || !strings.HasPrefix(names[0], "legacy-cache_5.news") {
+		t.Errorf("quarantined = %v, want the corrupt legacy file", names)
+	}
+
+	if _, err = os.Stat(filepath.Join(dir, FileName)); err != nil {
+		t.Errorf("cache file was not written: %v", err)
+	}
+}
+
+func TestStoreQuarantinesCorruptSlot(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `{"version":2,"slots":{
+		"2024-09-01T18":{"generatedAt":"2024-09-01T18:30:00Z","articles":[{"id":1,"timestamp":5,"topic":0,"title":"Bien"}]},
+		"2024-09-01T17":{"generatedAt":"2024-09-01T17:30:00Z","articles":[{"id":1,"timestamp":5,"topic":99,"title":"Tema desconocido"}]},
+		"2024-09-01T16":{"generatedAt":"2024-09-01T16:30:00Z","articles":"not a list"},
+		"2024-09-01T15":{"articles":[]},
+		"15":{"generatedAt":"2024-09-01T15:30:00Z","articles":[]}
+	}}`)
+
+	file, err := NewDirStore(dir).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Slots) != 1 {
+		t.Errorf("loaded %d slots, want 1", len(file.Slots))
+	}
+	if _, ok := file.Slot(testTime); !ok {
+		t.Error("valid slot was dropped")
+	}
+
+	if names := quarantined(t, dir); len(names) != 4 {
+		t.Errorf("quarantined = %v, want 4 slots", names)
+	}
+
+	// The corrupt slots are gone from the cache file, so they are only quarantined once.
+	if _, err = NewDirStore(dir).Load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if names := quarantined(t, dir); len(names) != 4 {
+		t.Errorf("quarantined after reload = %v", names)
+	}
+}
+
+func TestStoreQuarantinesCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `{"version":2,"slots":{"1":`)
+
+	file, err := NewDirStore(dir).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(file.Slots) != 0 {
+		t.Errorf("corrupt cache loaded %d slots", len(file.Slots))
+	}
+
+	if names := quarantined(t, dir); len(names) != 1 || !strings.HasPrefix(names[0], "cache.") {
+		t.Errorf("quarantined = %v, want the cache file", names)
+	}
+}
+
+func TestStoreRejectsNewerVersion(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `{"version":999,"slots":{}}`)
+
+	_, err := NewDirStore(dir).Load(context.Background())
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("err = %v, want ErrUnsupportedVersion", err)
+	}
+
+	// A newer cache must not be overwritten or quarantined.
+	data, _ := os.ReadFile(filepath.Join(dir, FileName))
+	if string(data) != `{"version":999,"slots":{}}` {
+		t.Error("newer cache file was modified")
+	}
+}
+
+func TestStoreMigratesVersion1(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, FileName), `{"version":1,"slots":{
+		"3":{"articles":[{"id":1,"timestamp":12975510,"topic":0,"title":"Uno"}]},
+		"4":{"articles":[]}
+	}}`)
+
+	file, err := NewDirStore(dir).Load(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slot, ok := file.Slot(testTime)
+	if !ok || !slot.GeneratedAt.Equal(testTime) || len(slot.Articles) != 1 {
+		t.Fatalf("migrated slot = %+v, %v", slot, ok)
+	}
+
+	if len(file.Slots) != 1 {
+		t.Errorf("empty version 1 slot was kept: %v", file.Slots)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, FileName))
+	if !strings.HasPrefix(string(data), `{"version":2,`) {
+		t.Errorf("cache file was not rewritten at version 2: %s", data)
+	}
+}
+
+func TestRecentRetention(t *testing.T) {
+	now := testTime
+	file := NewFile()
+	for _, age := range []time.Duration{0, time.Hour, 11 * time.Hour, 23 * time.Hour, 25 * time.Hour, 47 * time.Hour, 72 * time.Hour} {
+		file.SetSlot(now.Add(-age), Slot{Articles: []Entry{{Title: age.String()}}})
+	}
+
+	tests := []struct {
+		retention time.Duration
+		want      []string
+	}{
+		{12 * time.Hour, []string{"11h0m0s", "1h0m0s"}},
+		{24 * time.Hour, []string{"23h0m0s", "11h0m0s", "1h0m0s"}},
+		{48 * time.Hour, []string{"47h0m0s", "25h0m0s", "23h0m0s", "11h0m0s", "1h0m0s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.retention.String(), func(t *testing.T) {
+			var got []string
+			for _, slot := range file.Recent(now, tt.retention) {
+				got = append(got, slot.Articles[0].Title)
+			}
+
+			// The current hour's slot is never included.
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Recent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	file.Prune(now, 24*time.Hour)
+	if len(file.Slots) != 4 {
+		t.Errorf("Prune kept %d slots, want 4", len(file.Slots))
+	}
+}
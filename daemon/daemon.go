@@ -0,0 +1,142 @@
+// Package daemon generates news files on a cron schedule, for self-hosting without an external
+// scheduler such as EventBridge.
+package daemon
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/schedule"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	DefaultMinBackoff  = 30 * time.Second
+	DefaultMaxBackoff  = 5 * time.Minute
+	DefaultGracePeriod = 30 * time.Second
+)
+
+// ErrHourEnded is returned when a run still failing would retry past the end of its hour. The file
+// it was building is no use by then, and the next scheduled run covers the new hour.
+var ErrHourEnded = errors.New("hour ended before a file could be generated")
+
+// Daemon runs generation at every time its schedule matches, until stopped.
+type Daemon struct {
+	Schedule *schedule.Schedule
+	// Options configures generation. Its clock, in the first time zone, also drives the schedule,
+	// and Output receives every file.
+	Options generator.Options
+
+	// MinBackoff is the wait after the first failed attempt of a run. It doubles after every
+	// further failure, up to MaxBackoff.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// GracePeriod is how long a run in progress may continue once shutdown is requested.
+	GracePeriod time.Duration
+}
+
+func (d *Daemon) setDefaults() {
+	if d.Options.Clock == nil {
+		d.Options.Clock = generator.SystemClock{}
+	}
+
+	if d.MinBackoff <= 0 {
+		d.MinBackoff = DefaultMinBackoff
+	}
+
+	if d.MaxBackoff < d.MinBackoff {
+		d.MaxBackoff = max(DefaultMaxBackoff, d.MinBackoff)
+	}
+
+	if d.GracePeriod <= 0 {
+		d.GracePeriod = DefaultGracePeriod
+	}
+}
+
+// Run waits for every scheduled time and generates a file, starting with one straight away if
+// runNow is set. It returns nil once ctx is done; a run in progress is given GracePeriod to finish
+// first. Failed runs are logged, and only end the daemon if ctx is done.
+func (d *Daemon) Run(ctx context.Context, runNow bool) error {
+	d.setDefaults()
+
+	// Generation continues for the grace period after ctx is done, so a file that is almost
+	// written isn't thrown away.
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(d.GracePeriod, cancel)
+	})
+	defer stop()
+
+	if runNow {
+		d.logRun(d.run(ctx, work))
+	}
+
+	for {
+		now := d.Options.LocalNow()
+		next := d.Schedule.Next(now)
+		if next.IsZero() {
+			return errors.New("schedule never runs")
+		}
+
+		log.Printf("Next run at %s\n", next.Format(time.RFC3339))
+		select {
+		case <-ctx.Done():
+			log.Printf("Shutting down\n")
+			return nil
+		case <-time.After(next.Sub(now)):
+		}
+
+		d.logRun(d.run(ctx, work))
+		if ctx.Err() != nil {
+			log.Printf("Shutting down\n")
+			return nil
+		}
+	}
+}
+
+func (d *Daemon) logRun(result generator.Result, err error) {
+	if err != nil {
+		log.Printf("Scheduled run failed: %v\n", err)
+		return
+	}
+
+	for _, change := range result.Filtered {
+		log.Printf("Filter: %s\n", change)
+	}
+	log.Printf("Generated %s with %d articles\n", result.Location, result.NumberOfArticles)
+}
+
+// run generates the current hour's file, retrying with exponential backoff while the hour lasts.
+// Generation uses work; ctx only stops the retries.
+func (d *Daemon) run(ctx, work context.Context) (generator.Result, error) {
+	start := d.Options.LocalNow()
+	hourEnd := time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, start.Location()).Add(time.Hour)
+	backoff := d.MinBackoff
+
+	for attempt := 1; ; attempt++ {
+		result, err := generator.Generate(work, d.Options)
+		if err == nil {
+			return result, nil
+		}
+
+		if ctx.Err() != nil || work.Err() != nil {
+			return generator.Result{}, err
+		}
+
+		if !d.Options.Clock.Now().Add(backoff).Before(hourEnd) {
+			return generator.Result{}, fmt.Errorf("hour %02d: %w after %d attempts: %w", start.Hour(), ErrHourEnded, attempt, err)
+		}
+
+		log.Printf("Attempt %d failed, retrying in %s: %v\n", attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+			return generator.Result{}, err
+		case <-time.After(backoff):
+		}
+
+		backoff = min(backoff*2, d.MaxBackoff)
+	}
+}
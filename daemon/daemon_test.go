@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/schedule"
+	"WiiNewsPR/signing"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+// testSink records written files, after failing the first few writes.
+type testSink struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	files    map[string][]byte
+	// block, when set, holds every write until it is closed.
+	block   chan struct{}
+	started chan struct{}
+}
+
+func (s *testSink) Write(_ context.Context, key string, data []byte) (string, error) {
+	if s.block != nil {
+		close(s.started)
+		<-s.block
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.attempts++
+	if s.attempts <= s.failures {
+		return "", errors.New("bucket unavailable")
+	}
+
+	if s.files == nil {
+		s.files = make(map[string][]byte)
+	}
+	s.files[key] = data
+	return "test/" + key, nil
+}
+
+func newTestDaemon(t *testing.T, at time.Time, output *testSink) *Daemon {
+	t.Helper()
+
+	s, err := schedule.Parse(schedule.Hourly)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := "Las lluvias regresan al área metropolitana."
+	return &Daemon{
+		Schedule: s,
+		Options: generator.Options{
+			CacheDir: t.TempDir(),
+			Signer:   signing.Unsigned{},
+			Source:   &newstest.Source{Articles: []news.Article{{Title: "Vuelven las lluvias al área metro", Content: &body, Topic: news.NationalNews}}},
+			Clock:    generator.FixedClock(at),
+			Output:   output,
+		},
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 4 * time.Millisecond,
+	}
+}
+
+func TestRunRetries(t *testing.T) {
+	output := &testSink{failures: 2}
+	d := newTestDaemon(t, testTime, output)
+	d.setDefaults()
+
+	ctx := context.Background()
+	result, err := d.run(ctx, ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output.attempts != 3 {
+		t.Errorf("attempts = %d, want 3", output.attempts)
+	}
+	if _, ok := output.files["v2/1/049/news.bin.18"]; !ok || result.Location != "test/v2/1/049/news.bin.18" {
+		t.Errorf("files = %v, location = %q", output.files, result.Location)
+	}
+}
+
+func TestRunGivesUpAtEndOfHour(t *testing.T) {
+	output := &testSink{failures: 100}
+	d := newTestDaemon(t, time.Date(2024, time.September, 1, 18, 59, 0, 0, time.UTC), output)
+	d.MinBackoff = time.Minute
+	d.setDefaults()
+
+	ctx := context.Background()
+	_, err := d.run(ctx, ctx)
+	if !errors.Is(err, ErrHourEnded) {
+		t.Fatalf("err = %v, want ErrHourEnded", err)
+	}
+	if output.attempts != 1 {
+		t.Errorf("attempts = %d, want 1", output.attempts)
+	}
+}
+
+func TestRunStops(t *testing.T) {
+	d := newTestDaemon(t, testTime, &testSink{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error)
+	go func() { done <- d.Run(ctx, false) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after shutdown")
+	}
+}
+
+func TestRunFinishesInProgressRun(t *testing.T) {
+	output := &testSink{block: make(chan struct{}), started: make(chan struct{})}
+	d := newTestDaemon(t, testTime, output)
+	d.GracePeriod = time.Minute
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- d.Run(ctx, true) }()
+
+	<-output.started
+	cancel()
+	close(output.block)
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(output.files) != 1 {
+		t.Errorf("files = %v, want the run in progress to finish", output.files)
+	}
+}
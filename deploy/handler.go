@@ -1,65 +1,284 @@
 package main
 
 import (
+	"WiiNewsPR/backfill"
+	"WiiNewsPR/cache"
+	"WiiNewsPR/filter"
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/endi"
+	"WiiNewsPR/news/manual"
+	"WiiNewsPR/news/message"
+	"WiiNewsPR/news/nws"
+	"WiiNewsPR/objstore"
+	"WiiNewsPR/signing"
+	"WiiNewsPR/sink"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
-	"os/exec"
-	"time"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 )
 
-func Handler(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "./WiiNewsPR", "-o", "/tmp", "-c", "/tmp/cache")
+// Request is the invocation payload. The scheduled event carries none of these fields, so it
+// runs with the configuration from the environment.
+type Request struct {
+	// Targets overrides WIINEWSPR_TARGETS, e.g. ["1/049", "1/018"].
+	Targets []string `json:"targets,omitempty"`
+	// Backfill overrides WIINEWSPR_BACKFILL.
+	Backfill *bool `json:"backfill,omitempty"`
+}
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to run WiiNewsPR: %w\nOutput: %s", err, string(output))
+// File is a file uploaded by a run.
+type File struct {
+	Language uint8  `json:"language"`
+	Country  uint8  `json:"country"`
+	Hour     int    `json:"hour"`
+	Location string `json:"location"`
+	Size     int    `json:"size"`
+	Articles int    `json:"articles"`
+	// Rebuilt marks files the backfill built again from cached articles.
+	Rebuilt bool `json:"rebuilt,omitempty"`
+}
+
+// Response lists every file uploaded, and what failed.
+type Response struct {
+	Files  []File   `json:"files"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// Target is a language and country to generate files for.
+type Target struct {
+	Language uint8
+	Country  uint8
+}
+
+// parseTargets reads targets written as language/country, such as 1/049.
+func parseTargets(values []string) ([]Target, error) {
+	var targets []Target
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		language, country, ok := strings.Cut(value, "/")
+		languageCode, languageErr := strconv.ParseUint(language, 10, 8)
+		countryCode, countryErr := strconv.ParseUint(country, 10, 8)
+		if !ok || languageErr != nil || countryErr != nil || languageCode == 0 || countryCode == 0 {
+			return nil, fmt.Errorf("invalid target %q, want language/country such as 1/049", value)
+		}
+
+		targets = append(targets, Target{Language: uint8(languageCode), Country: uint8(countryCode)})
+	}
+
+	if len(targets) == 0 {
+		targets = append(targets, Target{Language: generator.DefaultLanguageCode, Country: generator.DefaultCountryCode})
+	}
+
+	return targets, nil
+}
+
+// bucketLayout stores files below the prefix without the leading v2/, as the bucket is served
+// from news/{lang}/{country}/news.bin.{hour}.
+type bucketLayout struct {
+	sink.Sink
+}
+
+func (b bucketLayout) Write(ctx context.Context, key string, data []byte) (string, error) {
+	return b.Sink.Write(ctx, strings.TrimPrefix(key, "v2/"), data)
+}
+
+func (b bucketLayout) Read(ctx context.Context, key string) ([]byte, error) {
+	return b.Sink.Read(ctx, strings.TrimPrefix(key, "v2/"))
+}
+
+// cacheLocation is where the cache of target is kept below location, a directory or an
+// s3://bucket/prefix URL. Every target has its own, as each records the articles of its own files.
+func cacheLocation(location string, target Target) string {
+	return fmt.Sprintf("%s/%d/%03d", strings.TrimSuffix(location, "/"), target.Language, target.Country)
+}
+
+// run generates the current hour's file for every target, and with doBackfill also rebuilds their
+// missing or expiring hours. Each target's cache is kept below cacheRoot. A failed target doesn't
+// stop the others.
+func run(ctx context.Context, opts generator.Options, cacheRoot string, output sink.Sink, targets []Target, doBackfill bool) (Response, error) {
+	response := Response{Files: []File{}}
+	var errs []error
+
+	for _, target := range targets {
+		opts := opts
+		opts.LanguageCode = target.Language
+		opts.CountryCode = target.Country
+		opts.Output = output
+
+		cacheStore, err := cache.Open(cacheLocation(cacheRoot, target))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d/%03d: %w", target.Language, target.Country, err))
+			continue
+		}
+		opts.CacheStore = cacheStore
+
+		result, err := generator.Generate(ctx, opts)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d/%03d: %w", target.Language, target.Country, err))
+			continue
+		}
+		for _, change := range result.Filtered {
+			log.Printf("Filter: %d/%03d: %s\n", target.Language, target.Country, change)
+		}
+		response.Files = append(response.Files, newFiles(result, false)...)
+
+		if !doBackfill {
+			continue
+		}
+
+		fills, err := (&backfill.Backfill{Options: opts, Sink: output}).Run(ctx)
+		for _, fill := range fills {
+			response.Files = append(response.Files, newFiles(fill.Result, !fill.Slot.IsZero())...)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%d/%03d backfill: %w", target.Language, target.Country, err))
+		}
 	}
 
-	hour := time.Now().Format("15")
-	filePath := fmt.Sprintf("/tmp/v2/1/049/news.bin.%s", hour)
+	for _, err := range errs {
+		response.Errors = append(response.Errors, err.Error())
+	}
+	return response, errors.Join(errs...)
+}
 
+// newFiles lists the copies of a result, one per local hour it was written for.
+func newFiles(result generator.Result, rebuilt bool) []File {
+	hours := result.Hours
+	if len(hours) == 0 {
+		hours = []int{result.Hour}
+	}
+
+	files := make([]File, 0, len(hours))
+	for i, hour := range hours {
+		file := File{
+			Language: result.LanguageCode,
+			Country:  result.CountryCode,
+			Hour:     hour,
+			Size:     len(result.Data),
+			Articles: result.NumberOfArticles,
+			Rebuilt:  rebuilt,
+		}
+		if i < len(result.Locations) {
+			file.Location = result.Locations[i]
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+// newSigner uses the signing service at WIINEWSPR_SIGNER_URL, so the private key stays out of the
+// function. Without it the key is read from WIINEWSPR_KEY, for deployments that bundle one.
+func newSigner() (signing.Signer, error) {
+	if url := os.Getenv("WIINEWSPR_SIGNER_URL"); url != "" {
+		return signing.NewRemoteSigner(url, os.Getenv("WIINEWSPR_SIGNER_TOKEN")), nil
+	}
+
+	return signing.NewPEMSigner(signing.KeyPathFromEnv())
+}
+
+// newSource combines the sources the way the command line does: the alerts with WIINEWSPR_ALERTS,
+// then the hand-written articles in WIINEWSPR_ARTICLES, then El Nuevo Día. They tell the time by
+// clock, like the generator. It returns nil when El Nuevo Día is the only source.
+func newSource(clock generator.Clock) news.Source {
+	var sources []news.Source
+	if os.Getenv("WIINEWSPR_ALERTS") == "true" {
+		sources = append(sources, nws.NewNWS(nws.WithNow(clock.Now)))
+	}
+	if dir := os.Getenv("WIINEWSPR_ARTICLES"); dir != "" {
+		sources = append(sources, manual.NewManual(dir, manual.WithNow(clock.Now)))
+	}
+
+	if len(sources) == 0 {
+		return nil
+	}
+	return news.Combine(append(sources, endi.NewEndi())...)
+}
+
+func Handler(ctx context.Context, req Request) (Response, error) {
 	bucketName := os.Getenv("S3_BUCKET")
 	if bucketName == "" {
-		return fmt.Errorf("S3_BUCKET environment variable is required")
+		return Response{}, fmt.Errorf("S3_BUCKET environment variable is required")
 	}
 
 	keyPrefix := os.Getenv("S3_PREFIX")
 	if keyPrefix == "" {
-		return fmt.Errorf("S3_PREFIX environment variable is required")
+		return Response{}, fmt.Errorf("S3_PREFIX environment variable is required")
+	}
+
+	if len(req.Targets) == 0 {
+		req.Targets = strings.Split(os.Getenv("WIINEWSPR_TARGETS"), ",")
 	}
 
-	cfg, err := config.LoadDefaultConfig(ctx)
+	targets, err := parseTargets(req.Targets)
 	if err != nil {
-		return fmt.Errorf("failed to load AWS config: %w", err)
+		return Response{}, err
 	}
 
-	uploader := manager.NewUploader(s3.NewFromConfig(cfg))
+	doBackfill := os.Getenv("WIINEWSPR_BACKFILL") == "true"
+	if req.Backfill != nil {
+		doBackfill = *req.Backfill
+	}
+
+	signer, err := newSigner()
+	if err != nil {
+		return Response{}, fmt.Errorf("failed to load signing key: %w", err)
+	}
 
-	file, err := os.Open(filePath)
+	// The cache defaults to /tmp, which only lasts as long as the Lambda instance. Point
+	// WIINEWSPR_CACHE at s3://bucket/prefix to keep it between instances.
+	cacheRoot := os.Getenv("WIINEWSPR_CACHE")
+	if cacheRoot == "" {
+		cacheRoot = "/tmp/cache"
+	}
+
+	client, prefix, err := objstore.FromURL(fmt.Sprintf("%s://%s/%s", objstore.Scheme, bucketName, keyPrefix))
 	if err != nil {
-		return fmt.Errorf("failed to open %s: %w", filePath, err)
+		return Response{}, err
 	}
-	defer file.Close()
 
-	_, err = uploader.Upload(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(fmt.Sprintf("%snews.bin.%s", keyPrefix, hour)),
-		Body:        file,
-		ContentType: aws.String("application/octet-stream"),
-	})
+	// The files are named after the consoles' local hour, so the zones are set explicitly rather
+	// than through TZ.
+	timeZones, err := generator.ParseTimeZones(os.Getenv("WIINEWSPR_TZ"))
 	if err != nil {
-		return fmt.Errorf("failed to upload: %w", err)
+		return Response{}, err
 	}
 
-	fmt.Printf("Uploaded news.bin.%s to S3 bucket %s\n", hour, bucketName)
-	return nil
+	messageConfig, err := message.ConfigFromEnv()
+	if err != nil {
+		return Response{}, err
+	}
+
+	messageSource, err := messageConfig.Source()
+	if err != nil {
+		return Response{}, err
+	}
+
+	var rules filter.Rules
+	if path := os.Getenv("WIINEWSPR_RULES"); path != "" {
+		if rules, err = filter.Load(path); err != nil {
+			return Response{}, err
+		}
+	}
+
+	clock := generator.SystemClock{}
+	opts := generator.Options{Signer: signer, Clock: clock, Source: newSource(clock), TimeZones: timeZones, Message: messageSource, Filter: rules}
+	response, err := run(ctx, opts, cacheRoot, bucketLayout{sink.NewObjectSink(client, prefix)}, targets, doBackfill)
+
+	summary, _ := json.Marshal(response)
+	log.Printf("%s\n", summary)
+	return response, err
 }
 
 func main() {
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/manual"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/news/nws"
+	"WiiNewsPR/objstore/objstoretest"
+	"WiiNewsPR/signing"
+	"WiiNewsPR/sink"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+func TestParseTargets(t *testing.T) {
+	targets, err := parseTargets([]string{"1/049", " 3/110 ", ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0] != (Target{1, 49}) || targets[1] != (Target{3, 110}) {
+		t.Errorf("targets = %v", targets)
+	}
+
+	if targets, err = parseTargets([]string{""}); err != nil || len(targets) != 1 || targets[0] != (Target{generator.DefaultLanguageCode, generator.DefaultCountryCode}) {
+		t.Errorf("default targets = %v, %v", targets, err)
+	}
+
+	for _, value := range []string{"1", "1/", "x/049", "1/300", "0/049"} {
+		if _, err = parseTargets([]string{value}); err == nil {
+			t.Errorf("parseTargets(%q) succeeded", value)
+		}
+	}
+}
+
+func TestCacheLocation(t *testing.T) {
+	for location, want := range map[string]string{
+		"/tmp/cache":         "/tmp/cache/1/049",
+		"s3://wii/cache/":    "s3://wii/cache/1/049",
+		"s3://wii.rauln.com": "s3://wii.rauln.com/1/049",
+	} {
+		if got := cacheLocation(location, Target{1, 49}); got != want {
+			t.Errorf("cacheLocation(%q) = %q, want %q", location, got, want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	server := objstoretest.NewServer("wii")
+	defer server.Close()
+
+	body := "Las lluvias regresan al área metropolitana."
+	opts := generator.Options{
+		Signer: signing.Unsigned{},
+		Source: &newstest.Source{Articles: []news.Article{{Title: "Vuelven las lluvias", Content: &body, Topic: news.NationalNews}}},
+		Clock:  generator.FixedClock(testTime),
+	}
+	output := bucketLayout{sink.NewObjectSink(server.Client(), "news/")}
+	cacheRoot := t.TempDir()
+
+	response, err := run(context.Background(), opts, cacheRoot, output, []Target{{1, 49}, {1, 18}}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(response.Files) != 2 {
+		t.Fatalf("files = %+v", response.Files)
+	}
+	for i, want := range []string{"s3://wii/news/1/049/news.bin.18", "s3://wii/news/1/018/news.bin.18"} {
+		if file := response.Files[i]; file.Location != want || file.Hour != 18 || file.Articles != 1 || file.Size == 0 {
+			t.Errorf("file %d = %+v, want it at %s", i, file, want)
+		}
+		if _, ok := server.Object(want[len("s3://wii/"):]); !ok {
+			t.Errorf("%s was not uploaded", want)
+		}
+	}
+
+	// Each target records its articles in a cache of its own.
+	for _, dir := range []string{"1/049", "1/018"} {
+		if _, err = os.Stat(filepath.Join(cacheRoot, dir, cache.FileName)); err != nil {
+			t.Errorf("cache of %s: %v", dir, err)
+		}
+	}
+
+	// The backfill uploads the other 23 hours from the cached articles.
+	response, err = run(context.Background(), opts, cacheRoot, output, []Target{{1, 49}}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Files) != 24 || response.Files[0].Rebuilt || !response.Files[1].Rebuilt {
+		t.Errorf("%d files, first %+v", len(response.Files), response.Files[0])
+	}
+}
+
+func TestRunReportsFailures(t *testing.T) {
+	server := objstoretest.NewServer("wii")
+	defer server.Close()
+
+	opts := generator.Options{
+		Signer: signing.Unsigned{},
+		Source: &newstest.Source{Err: errors.New("feed down")},
+		Clock:  generator.FixedClock(testTime),
+	}
+
+	response, err := run(context.Background(), opts, t.TempDir(), bucketLayout{sink.NewObjectSink(server.Client(), "news/")}, []Target{{1, 49}}, false)
+	if err == nil || len(response.Errors) != 1 || len(response.Files) != 0 {
+		t.Errorf("response = %+v, err = %v", response, err)
+	}
+}
+
+func TestNewSigner(t *testing.T) {
+	t.Setenv("WIINEWSPR_SIGNER_URL", "https://signer.example/sign")
+	t.Setenv("WIINEWSPR_SIGNER_TOKEN", "secret")
+	t.Setenv("WIINEWSPR_KEY", "/nonexistent/Private.pem")
+
+	signer, err := newSigner()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remote, ok := signer.(*signing.RemoteSigner); !ok || remote.URL != "https://signer.example/sign" || remote.Token != "secret" {
+		t.Errorf("signer = %#v", signer)
+	}
+
+	t.Setenv("WIINEWSPR_SIGNER_URL", "")
+	if _, err = newSigner(); err == nil {
+		t.Error("missing key file was accepted")
+	}
+}
+
+func TestNewSource(t *testing.T) {
+	t.Setenv("WIINEWSPR_ALERTS", "")
+	t.Setenv("WIINEWSPR_ARTICLES", "")
+	if source := newSource(generator.FixedClock(testTime)); source != nil {
+		t.Errorf("source = %#v, want the default", source)
+	}
+
+	// Like the command line, the alerts lead, then the hand-written articles.
+	t.Setenv("WIINEWSPR_ALERTS", "true")
+	t.Setenv("WIINEWSPR_ARTICLES", t.TempDir())
+	combined, ok := newSource(generator.FixedClock(testTime)).(news.Combined)
+	if !ok || len(combined) != 3 {
+		t.Fatalf("source = %#v", combined)
+	}
+	if _, ok = combined[0].(*nws.NWS); !ok {
+		t.Errorf("first source = %T, want the alerts", combined[0])
+	}
+	if _, ok = combined[1].(*manual.Manual); !ok {
+		t.Errorf("second source = %T, want the hand-written articles", combined[1])
+	}
+}
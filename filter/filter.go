@@ -0,0 +1,253 @@
+// Package filter applies content rules to the fetched articles before they are selected, to keep
+// sponsored posts, obituaries and the like off the Wii, or to fix how an article is shown.
+package filter
+
+import (
+	"WiiNewsPR/news"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Action is what a rule does to the articles it matches.
+type Action string
+
+const (
+	// Drop removes the article.
+	Drop Action = "drop"
+	// Demote moves the article after the others and unpins it.
+	Demote Action = "demote"
+	// Retopic moves the article to Rule.Topic.
+	Retopic Action = "retopic"
+	// RewriteTitle replaces the title. With a title pattern, its matches are replaced with
+	// Rule.Replacement, which can refer to groups as $1. Without one, the whole title is.
+	RewriteTitle Action = "rewrite-title"
+)
+
+// Match is what an article must have for a rule to apply. Every condition that is set must hold.
+type Match struct {
+	// Title and Body are regular expressions. Use (?i) to ignore case.
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+	// Category, Author and Feed are compared ignoring case. Category matches any of the
+	// article's categories.
+	Category string `json:"category,omitempty"`
+	Author   string `json:"author,omitempty"`
+	Feed     string `json:"feed,omitempty"`
+	// Keywords match when any of them appears in the title or body, ignoring case.
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+// Rule is a named match and the action taken on matching articles.
+type Rule struct {
+	Name   string `json:"name"`
+	Match  Match  `json:"match"`
+	Action Action `json:"action"`
+	// Topic is the new topic for Retopic, by name, such as "sports".
+	Topic string `json:"topic,omitempty"`
+	// Replacement is the new title, or what the title pattern's matches become, for RewriteTitle.
+	// A rewrite that would leave the title empty is skipped.
+	Replacement string `json:"replacement,omitempty"`
+
+	title *regexp.Regexp
+	body  *regexp.Regexp
+	topic news.Topic
+}
+
+// Rules are applied in order. A dropped article isn't matched against later rules.
+type Rules []*Rule
+
+// Load reads rules from a JSON file holding a list of rules.
+func Load(path string) (Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Parse decodes and checks a JSON list of rules.
+func Parse(data []byte) (Rules, error) {
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+
+	for i, rule := range rules {
+		if err := rule.compile(); err != nil {
+			return nil, fmt.Errorf("rule %d (%s): %w", i+1, rule.Name, err)
+		}
+	}
+	return rules, nil
+}
+
+// compile checks the rule and prepares its patterns.
+func (r *Rule) compile() error {
+	if r.Name == "" {
+		return errors.New("missing name")
+	}
+
+	m := r.Match
+	if m.Title == "" && m.Body == "" && m.Category == "" && m.Author == "" && m.Feed == "" && len(m.Keywords) == 0 {
+		return errors.New("matches every article")
+	}
+
+	var err error
+	if m.Title != "" {
+		if r.title, err = regexp.Compile(m.Title); err != nil {
+			return fmt.Errorf("title: %w", err)
+		}
+	}
+
+	if m.Body != "" {
+		if r.body, err = regexp.Compile(m.Body); err != nil {
+			return fmt.Errorf("body: %w", err)
+		}
+	}
+
+	switch r.Action {
+	case Drop, Demote:
+	case Retopic:
+		if r.topic, err = news.ParseTopic(r.Topic); err != nil {
+			return err
+		}
+	case RewriteTitle:
+		if r.title == nil && r.Replacement == "" {
+			return errors.New("rewrite-title needs a title pattern or a replacement")
+		}
+	default:
+		return fmt.Errorf("unknown action %q, want drop, demote, retopic or rewrite-title", r.Action)
+	}
+
+	return nil
+}
+
+// Matches reports whether the article meets every condition of the rule.
+func (r *Rule) Matches(article news.Article) bool {
+	var body string
+	if article.Content != nil {
+		body = *article.Content
+	}
+
+	m := r.Match
+	if r.title != nil && !r.title.MatchString(article.Title) {
+		return false
+	}
+	if r.body != nil && !r.body.MatchString(body) {
+		return false
+	}
+	if m.Author != "" && !strings.EqualFold(m.Author, article.Author) {
+		return false
+	}
+	if m.Feed != "" && !strings.EqualFold(m.Feed, article.Feed) {
+		return false
+	}
+	if m.Category != "" && !containsFold(article.Categories, m.Category) {
+		return false
+	}
+	if len(m.Keywords) > 0 && !hasKeyword(article.Title+"\n"+body, m.Keywords) {
+		return false
+	}
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasKeyword(text string, keywords []string) bool {
+	text = strings.ToLower(text)
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Change records a rule that affected an article, for the log.
+type Change struct {
+	Rule   string
+	Action Action
+	// Title is the article's title before the rule applied.
+	Title string
+	// Result describes the change, such as the new topic or title.
+	Result string
+}
+
+func (c Change) String() string {
+	if c.Result == "" {
+		return fmt.Sprintf("rule %q: %s %q", c.Rule, c.Action, c.Title)
+	}
+	return fmt.Sprintf("rule %q: %s %q to %s", c.Rule, c.Action, c.Title, c.Result)
+}
+
+// Apply runs the rules over the articles, in order, and returns the articles left with every
+// change made. Demoted articles are moved after the others, keeping their order.
+func (rules Rules) Apply(articles []news.Article) ([]news.Article, []Change) {
+	var kept, demoted []news.Article
+	var changes []Change
+
+	for _, article := range articles {
+		dropped := false
+		for _, rule := range rules {
+			if !rule.Matches(article) {
+				continue
+			}
+
+			change := Change{Rule: rule.Name, Action: rule.Action, Title: article.Title}
+			switch rule.Action {
+			case Drop:
+				dropped = true
+			case Demote:
+				article.Demoted = true
+				article.Pinned = false
+			case Retopic:
+				article.Topic = rule.topic
+				change.Result = rule.topic.String()
+			case RewriteTitle:
+				title := rule.Replacement
+				if rule.title != nil {
+					title = rule.title.ReplaceAllString(article.Title, rule.Replacement)
+				}
+
+				// An article without a title would leave an empty headline on the Wii.
+				if title = strings.TrimSpace(title); title == "" {
+					log.Printf("Warning: Rule %q would leave %q without a title, skipping it\n", rule.Name, article.Title)
+					continue
+				}
+				article.Title = title
+				change.Result = fmt.Sprintf("%q", article.Title)
+			}
+
+			changes = append(changes, change)
+			if dropped {
+				break
+			}
+		}
+
+		switch {
+		case dropped:
+		case article.Demoted:
+			demoted = append(demoted, article)
+		default:
+			kept = append(kept, article)
+		}
+	}
+
+	return append(kept, demoted...), changes
+}
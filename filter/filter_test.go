@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"WiiNewsPR/news"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	rules, err := Load("testdata/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := func(s string) *string { return &s }
+	articles := []news.Article{
+		{Title: "Tienda abre su cuarta sucursal", Content: text("Contenido auspiciado por la tienda."), Topic: news.Business, Pinned: true},
+		{Title: "Horóscopo del 2 de septiembre", Topic: news.Entertainment, Feed: "entretenimiento"},
+		{Title: "Exclusiva: Bad Bunny anuncia gira", Topic: news.Entertainment},
+		{Title: "Vaguada trae lluvias a la isla", Topic: news.Science, Feed: "ciencia-ambiente", Categories: []string{"clima"}},
+		{Title: "Esquela de Juan del Pueblo", Topic: news.NationalNews, Categories: []string{"Locales", "Obituarios"}},
+		{Title: "Cangrejeros ganan el primer juego de la final", Topic: news.Sports},
+	}
+
+	filtered, changes := rules.Apply(articles)
+
+	var titles []string
+	for _, article := range filtered {
+		titles = append(titles, article.Title)
+	}
+	want := []string{"Bad Bunny anuncia gira", "Vaguada trae lluvias a la isla", "Cangrejeros ganan el primer juego de la final", "Tienda abre su cuarta sucursal"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+
+	if filtered[1].Topic != news.NationalNews {
+		t.Errorf("retopic = %v, want national", filtered[1].Topic)
+	}
+	if sponsored := filtered[3]; !sponsored.Demoted || sponsored.Pinned {
+		t.Errorf("demoted = %+v", sponsored)
+	}
+
+	var log []string
+	for _, change := range changes {
+		log = append(log, change.String())
+	}
+	wantLog := []string{
+		`rule "sponsored": demote "Tienda abre su cuarta sucursal"`,
+		`rule "horoscope": drop "Horóscopo del 2 de septiembre"`,
+		`rule "exclusive": rewrite-title "Exclusiva: Bad Bunny anuncia gira" to "Bad Bunny anuncia gira"`,
+		`rule "weather": retopic "Vaguada trae lluvias a la isla" to national`,
+		`rule "obituaries": drop "Esquela de Juan del Pueblo"`,
+	}
+	if !reflect.DeepEqual(log, wantLog) {
+		t.Errorf("changes = %q, want %q", log, wantLog)
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	article := news.Article{Title: "Recetas para el fin de semana", Author: "Redacción", Feed: "estilos"}
+
+	for _, test := range []struct {
+		match Match
+		want  bool
+	}{
+		{Match{Author: "redacción"}, true},
+		{Match{Author: "redacción", Feed: "deportes"}, false},
+		{Match{Keywords: []string{"receta", "menú"}}, true},
+		{Match{Title: "^Recetas", Body: "."}, false},
+		{Match{Category: "Estilos"}, false},
+	} {
+		rule := &Rule{Name: "test", Match: test.match, Action: Drop}
+		if err := rule.compile(); err != nil {
+			t.Fatal(err)
+		}
+		if got := rule.Matches(article); got != test.want {
+			t.Errorf("%+v matches = %v, want %v", test.match, got, test.want)
+		}
+	}
+}
+
+func TestRewriteTitleNotEmpty(t *testing.T) {
+	rules, err := Parse([]byte(`[{"name": "exclusive", "match": {"title": "^(?i:exclusiva):\\s*"}, "action": "rewrite-title", "replacement": ""}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Stripping the label would leave nothing, so the title is kept.
+	filtered, changes := rules.Apply([]news.Article{{Title: "Exclusiva: "}})
+	if len(filtered) != 1 || filtered[0].Title != "Exclusiva: " || len(changes) != 0 {
+		t.Errorf("articles = %+v, changes = %v", filtered, changes)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		rules string
+		err   string
+	}{
+		{`[{"match": {"feed": "x"}, "action": "drop"}]`, "missing name"},
+		{`[{"name": "all", "action": "drop"}]`, "matches every article"},
+		{`[{"name": "bad", "match": {"title": "("}, "action": "drop"}]`, "title"},
+		{`[{"name": "hide", "match": {"feed": "x"}, "action": "hide"}]`, "unknown action"},
+		{`[{"name": "move", "match": {"feed": "x"}, "action": "retopic", "topic": "gossip"}]`, "gossip"},
+		{`[{"name": "rename", "match": {"feed": "x"}, "action": "rewrite-title"}]`, "rewrite-title"},
+	} {
+		_, err := Parse([]byte(test.rules))
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: err = %v, want %q", test.rules, err, test.err)
+		}
+	}
+}
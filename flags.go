@@ -0,0 +1,130 @@
+package main
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/filter"
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/endi"
+	"WiiNewsPR/news/manual"
+	"WiiNewsPR/news/message"
+	"WiiNewsPR/news/nws"
+	"WiiNewsPR/signing"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+// generateFlags are the flags of every command that generates files.
+type generateFlags struct {
+	cacheDir       *string
+	keyPath        *string
+	signerURL      *string
+	unsigned       *bool
+	retention      *time.Duration
+	lockTimeout    *time.Duration
+	titleThreshold *float64
+	bodyThreshold  *float64
+	timeZones      *string
+	message        *string
+	messageExpires *string
+	messageFile    *string
+	messageFeed    *string
+	messageLife    *time.Duration
+	alerts         *bool
+	alertsURL      *string
+	articlesDir    *string
+	rulesPath      *string
+}
+
+func addGenerateFlags(flags *flag.FlagSet) *generateFlags {
+	return &generateFlags{
+		cacheDir:       flags.String("c", "./cache", "Cache directory, or s3://bucket/prefix, for articles generated previously (default: ./cache)"),
+		keyPath:        flags.String("k", signing.KeyPathFromEnv(), "RSA private key used to sign the file (default: $WIINEWSPR_KEY or Private.pem)"),
+		signerURL:      flags.String("signer", os.Getenv("WIINEWSPR_SIGNER_URL"), "URL of a remote signing service, used instead of -k (default: $WIINEWSPR_SIGNER_URL)"),
+		unsigned:       flags.Bool("unsigned", false, "Write a zeroed signature instead of signing (for patched channels and emulators)"),
+		retention:      flags.Duration("retention", cache.DefaultRetention, "How far back cached articles are included in the topic timestamps and duplicate checks"),
+		lockTimeout:    flags.Duration("lock-timeout", 0, "How long to wait for another generation using the same cache, instead of failing straight away"),
+		titleThreshold: flags.Float64("title-threshold", news.DefaultTitleThreshold, "Normalized title similarity (0-1) from which two articles are the same story"),
+		bodyThreshold:  flags.Float64("body-threshold", news.DefaultBodyThreshold, "Share of body shingles (0-1) two articles need in common to be the same story"),
+		message:        flags.String("message", os.Getenv("WIINEWSPR_MESSAGE"), "Urgent message shown at the top of the channel until -message-expires (default: $WIINEWSPR_MESSAGE)"),
+		messageExpires: flags.String("message-expires", os.Getenv("WIINEWSPR_MESSAGE_EXPIRES"), "RFC 3339 time the -message expires at (default: $WIINEWSPR_MESSAGE_EXPIRES)"),
+		messageFile:    flags.String("message-file", os.Getenv("WIINEWSPR_MESSAGE_FILE"), "Text file read on every run for the urgent message, optionally starting with an \"Expires:\" line (default: $WIINEWSPR_MESSAGE_FILE)"),
+		messageFeed:    flags.String("message-feed", os.Getenv("WIINEWSPR_MESSAGE_FEED"), "RSS or Atom feed whose newest entry is the urgent message (default: $WIINEWSPR_MESSAGE_FEED)"),
+		messageLife:    flags.Duration("message-lifetime", message.DefaultLifetime, "How long a message from -message-file or -message-feed without an expiry is shown"),
+		alerts:         flags.Bool("alerts", os.Getenv("WIINEWSPR_ALERTS") == "true", "Lead with the National Weather Service alerts in effect for Puerto Rico (default: $WIINEWSPR_ALERTS is true)"),
+		alertsURL:      flags.String("alerts-url", nws.DefaultURL, "CAP Atom feed the -alerts are read from"),
+		articlesDir:    flags.String("articles", os.Getenv("WIINEWSPR_ARTICLES"), "Directory of hand-written Markdown or YAML articles to add to the feeds' (default: $WIINEWSPR_ARTICLES)"),
+		rulesPath:      flags.String("rules", os.Getenv("WIINEWSPR_RULES"), "JSON file of content rules that drop, demote, retopic or retitle articles before they are selected (default: $WIINEWSPR_RULES)"),
+		timeZones:      flags.String("tz", os.Getenv("WIINEWSPR_TZ"), "Comma-separated time zones of the consoles, e.g. America/Puerto_Rico, to write the file for each one's local hour (default: $WIINEWSPR_TZ or the system zone)"),
+	}
+}
+
+// options builds the generator options from the parsed flags. The sources tell the time by clock
+// too, so a run with -at is the same whenever it happens.
+func (f *generateFlags) options(clock generator.Clock) (generator.Options, error) {
+	signer, err := newSigner(*f.keyPath, *f.signerURL, *f.unsigned)
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	cacheStore, err := cache.Open(*f.cacheDir)
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	timeZones, err := generator.ParseTimeZones(*f.timeZones)
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	config := message.Config{Text: *f.message, File: *f.messageFile, FeedURL: *f.messageFeed, Lifetime: *f.messageLife}
+	if *f.messageExpires != "" {
+		if config.Expires, err = time.Parse(time.RFC3339, *f.messageExpires); err != nil {
+			return generator.Options{}, fmt.Errorf("invalid -message-expires: %w", err)
+		}
+	}
+
+	messageSource, err := config.Source()
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	var rules filter.Rules
+	if *f.rulesPath != "" {
+		if rules, err = filter.Load(*f.rulesPath); err != nil {
+			return generator.Options{}, err
+		}
+	}
+
+	// Alerts lead, then the hand-written articles, so they win over the same story from a feed.
+	var sources []news.Source
+	if *f.alerts {
+		sources = append(sources, nws.NewNWS(nws.WithURL(*f.alertsURL), nws.WithNow(clock.Now)))
+	}
+	if *f.articlesDir != "" {
+		sources = append(sources, manual.NewManual(*f.articlesDir, manual.WithNow(clock.Now)))
+	}
+
+	var source news.Source
+	if len(sources) > 0 {
+		source = news.Combine(append(sources, endi.NewEndi())...)
+	}
+
+	return generator.Options{
+		CacheStore:  cacheStore,
+		Source:      source,
+		Clock:       clock,
+		TimeZones:   timeZones,
+		Message:     messageSource,
+		Filter:      rules,
+		LockTimeout: *f.lockTimeout,
+		Retention:   *f.retention,
+		Signer:      signer,
+		Dedup: generator.DedupOptions{
+			TitleThreshold: *f.titleThreshold,
+			BodyThreshold:  *f.bodyThreshold,
+		},
+	}, nil
+}
@@ -1,10 +1,13 @@
-package main
+package generator
 
 import (
 	"math"
-	"unicode/utf16"
+	"sort"
 )
 
+// NoPicture is the picture index of articles without an image.
+const NoPicture = math.MaxUint32
+
 type Article struct {
 	ID                uint32
 	SourceIndex       uint32
@@ -28,24 +31,61 @@ type Image struct {
 	PictureOffset uint32
 }
 
+// articleIDs numbers the articles of this hour. Updates keep the ID of the story they replace, and
+// the other articles count up from 1 around them.
+func (n *News) articleIDs() []uint32 {
+	used := map[uint32]bool{}
+	for _, update := range n.updates {
+		used[update.ID] = true
+	}
+
+	ids := make([]uint32, len(n.articles))
+	next := uint32(1)
+	for i := range n.articles {
+		if update, ok := n.updates[i]; ok {
+			ids[i] = update.ID
+			continue
+		}
+
+		for used[next] {
+			next++
+		}
+		ids[i] = next
+		next++
+	}
+
+	return ids
+}
+
+// pinFirst moves the pinned articles before the others, keeping the order within each. It runs
+// before deduplication, so a pinned article wins over a duplicate from a feed.
+func (n *News) pinFirst() {
+	sort.SliceStable(n.articles, func(i, j int) bool {
+		return n.articles[i].Pinned && !n.articles[j].Pinned
+	})
+}
+
 func (n *News) MakeArticleTable() {
 	n.Header.ArticleTableOffset = n.GetCurrentSize()
 
+	ids := n.articleIDs()
+	locationIndexes := n.placeArticles()
+
 	// First write all metadata
 	for i, article := range n.articles {
-		publishedTime := currentTime
-
-		// FORK UPDATE: Always use San Juan location (index 0)
-		locationIndex := uint32(0)
+		publishedTime := fixTime(n.currentTime)
+		if update, ok := n.updates[i]; ok {
+			publishedTime = update.PublishedAt()
+		}
 
 		n.Articles = append(n.Articles, Article{
-			ID:                uint32(i + 1),
+			ID:                ids[i],
 			SourceIndex:       0,
-			LocationIndex:     locationIndex,
+			LocationIndex:     locationIndexes[i],
 			PictureTimestamp:  0,
-			PictureIndex:      math.MaxUint32,
-			PublishedTime:     fixTime(publishedTime),
-			UpdatedTime:       fixTime(currentTime),
+			PictureIndex:      NoPicture,
+			PublishedTime:     publishedTime,
+			UpdatedTime:       fixTime(n.currentTime),
 			HeadlineSize:      0,
 			HeadlineOffset:    0,
 			ArticleTextSize:   0,
@@ -53,15 +93,20 @@ func (n *News) MakeArticleTable() {
 		})
 
 		n.timestamps[article.Topic+1] = append(n.timestamps[article.Topic+1], Timestamp{
-			Time:          fixTime(currentTime),
-			ArticleNumber: uint32(i + 1),
+			Time:          fixTime(n.currentTime),
+			ArticleNumber: ids[i],
 		})
 	}
 
 	// Next write the text
 	for i, article := range n.articles {
-		encodedTitle := utf16.Encode([]rune(article.Title))
-		encodedArticle := utf16.Encode([]rune(*article.Content))
+		encodedTitle := encodeText(article.Title)
+
+		// Articles without a description still need a body, even if empty.
+		var encodedArticle []uint16
+		if article.Content != nil {
+			encodedArticle = encodeText(*article.Content)
+		}
 
 		n.Articles[i].HeadlineSize = uint32(len(encodedTitle) * 2)
 		n.Articles[i].ArticleTextSize = uint32(len(encodedArticle) * 2)
@@ -124,7 +169,7 @@ func (n *News) WriteImages() {
 		}
 
 		n.Articles[articleIndex].PictureIndex = uint32(imageIndex)
-		n.Articles[articleIndex].PictureTimestamp = fixTime(currentTime)
+		n.Articles[articleIndex].PictureTimestamp = fixTime(n.currentTime)
 	}
 
 	for imageIndex, articleIndex := range articlesWithImages {
@@ -132,9 +177,9 @@ func (n *News) WriteImages() {
 
 		// Only process caption if it exists
 		if article.Thumbnail.Caption != "" {
-			caption := utf16.Encode([]rune(article.Thumbnail.Caption))
+			caption := encodeText(article.Thumbnail.Caption)
 			n.Images[imageIndex].CaptionOffset = n.GetCurrentSize()
-			n.Images[imageIndex].CaptionSize = uint32(len(caption) / 2)
+			n.Images[imageIndex].CaptionSize = uint32(len(caption) * 2)
 			n.CaptionData = append(n.CaptionData, caption...)
 			n.CaptionData = append(n.CaptionData, 0)
 
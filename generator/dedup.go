@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/news"
+	"fmt"
+)
+
+// DedupOptions tunes how articles are recognised as the same story.
+type DedupOptions struct {
+	// TitleThreshold is the normalized title similarity from which two articles are the same
+	// story. Defaults to news.DefaultTitleThreshold.
+	TitleThreshold float64
+	// BodyThreshold is the estimated share of body shingles from which two articles are the same
+	// story. Defaults to news.DefaultBodyThreshold.
+	BodyThreshold float64
+}
+
+func (o *DedupOptions) setDefaults() {
+	if o.TitleThreshold <= 0 {
+		o.TitleThreshold = news.DefaultTitleThreshold
+	}
+
+	if o.BodyThreshold <= 0 {
+		o.BodyThreshold = news.DefaultBodyThreshold
+	}
+}
+
+// Merge records an article that was dropped as a duplicate of another, or re-emitted as an update.
+type Merge struct {
+	// Title is the article that was dropped or re-emitted.
+	Title string
+	// Into is the article it duplicated.
+	Into string
+	// Past is set when Into was written in a previous hour.
+	Past bool
+	// Updated is set when the body of a story from a previous hour changed materially, so Title was
+	// re-emitted as an update of Into instead of being dropped.
+	Updated bool
+	// Reason is "title" or "body", whichever crossed its threshold.
+	Reason     string
+	Similarity float64
+}
+
+func (m Merge) String() string {
+	when := "this hour"
+	if m.Past {
+		when = "a previous hour"
+	}
+
+	if m.Updated {
+		return fmt.Sprintf("%q re-emitted as an update of %q from %s (%s similarity %.2f)", m.Title, m.Into, when, m.Reason, m.Similarity)
+	}
+
+	return fmt.Sprintf("%q merged into %q from %s (%s similarity %.2f)", m.Title, m.Into, when, m.Reason, m.Similarity)
+}
+
+// candidate is an article a new one is compared against.
+type candidate struct {
+	title       string
+	fingerprint news.Fingerprint
+	// index is the position in the kept articles, or -1 for cached articles.
+	index int
+	// entry is the cached article, for candidates from previous hours.
+	entry cache.Entry
+}
+
+// deduplicate drops the articles that are the same story as an article of a previous hour, or as
+// an earlier article of this hour from any source. A duplicate from this hour hands over the
+// picture and body its twin was missing. A story from a previous hour whose body changed, or that
+// is pinned, is kept as an update of it instead.
+func (n *News) deduplicate(opts DedupOptions) {
+	opts.setDefaults()
+
+	var candidates []candidate
+	for _, entry := range n.pastEntries {
+		candidates = append(candidates, candidate{title: entry.Title, fingerprint: entry.Fingerprint, index: -1, entry: entry})
+	}
+
+	var kept []news.Article
+	n.updates = map[int]cache.Entry{}
+	for _, article := range n.articles {
+		fingerprint := news.BodyFingerprint(articleBody(article))
+
+		merge, twin := findDuplicate(article.Title, fingerprint, candidates, opts)
+		if merge != nil && merge.Past && !n.isUpdated(twin.entry) {
+			// A pinned story is written again every hour under its own ID, so it keeps leading the
+			// Wii Menu for as long as its source has it.
+			changed := bodyChanged(article, fingerprint, twin.entry, opts)
+			if changed || article.Pinned {
+				if changed {
+					merge.Updated = true
+					n.merges = append(n.merges, *merge)
+				}
+				n.updates[len(kept)] = twin.entry
+				merge = nil
+			}
+		}
+
+		if merge == nil {
+			candidates = append(candidates, candidate{title: article.Title, fingerprint: fingerprint, index: len(kept)})
+			kept = append(kept, article)
+			continue
+		}
+
+		if twin.index >= 0 {
+			mergeInto(&kept[twin.index], article)
+		}
+		n.merges = append(n.merges, *merge)
+	}
+
+	n.articles = kept
+	n.dropSupersededTimestamps()
+}
+
+// bodyChanged reports whether article has a materially different body than the cached version of
+// the story: one that has less than opts.BodyThreshold in common with it, so a corrected typo or
+// byline doesn't bump the story on the Wii every hour. Entries cached before content hashes were
+// recorded never count as changed.
+func bodyChanged(article news.Article, fingerprint news.Fingerprint, entry cache.Entry, opts DedupOptions) bool {
+	hash := news.ContentHash(articleBody(article))
+	if entry.ContentHash == "" || hash == "" || hash == entry.ContentHash {
+		return false
+	}
+
+	return fingerprint.Similarity(entry.Fingerprint) < opts.BodyThreshold
+}
+
+// isUpdated reports whether an article of this hour already updates the story of entry.
+func (n *News) isUpdated(entry cache.Entry) bool {
+	for _, updated := range n.updates {
+		if updated.SameStory(entry) {
+			return true
+		}
+	}
+	return false
+}
+
+// dropSupersededTimestamps removes the previous versions of updated stories from the timestamp
+// table, so the Wii only lists the version from this hour.
+func (n *News) dropSupersededTimestamps() {
+	for _, entry := range n.updates {
+		topic := entry.Topic + 1
+		timestamps := n.timestamps[topic][:0]
+		for _, timestamp := range n.timestamps[topic] {
+			if timestamp.ArticleNumber != entry.ID || timestamp.Time != entry.Timestamp {
+				timestamps = append(timestamps, timestamp)
+			}
+		}
+		n.timestamps[topic] = timestamps
+	}
+}
+
+// findDuplicate returns the first candidate that is the same story, and why.
+func findDuplicate(title string, fingerprint news.Fingerprint, candidates []candidate, opts DedupOptions) (*Merge, candidate) {
+	for _, c := range candidates {
+		reason := "title"
+		similarity := news.TitleSimilarity(title, c.title)
+		if similarity < opts.TitleThreshold {
+			reason = "body"
+			similarity = fingerprint.Similarity(c.fingerprint)
+			if similarity < opts.BodyThreshold {
+				continue
+			}
+		}
+
+		return &Merge{Title: title, Into: c.title, Past: c.index < 0, Reason: reason, Similarity: similarity}, c
+	}
+
+	return nil, candidate{}
+}
+
+// mergeInto fills in what kept is missing from its duplicate.
+func mergeInto(kept *news.Article, duplicate news.Article) {
+	if kept.Thumbnail == nil {
+		kept.Thumbnail = duplicate.Thumbnail
+	}
+
+	if kept.Content == nil {
+		kept.Content = duplicate.Content
+	}
+
+	if kept.Location == nil {
+		kept.Location = duplicate.Location
+	}
+}
+
+func articleBody(article news.Article) string {
+	if article.Content == nil {
+		return ""
+	}
+	return *article.Content
+}
+
+// cacheEntry is what the cache remembers about article i of this hour.
+func (n *News) cacheEntry(i int) cache.Entry {
+	article := n.articles[i]
+	return cache.Entry{
+		ID:          n.Articles[i].ID,
+		Timestamp:   fixTime(n.currentTime),
+		Topic:       article.Topic,
+		Title:       article.Title,
+		Fingerprint: news.BodyFingerprint(articleBody(article)),
+		ContentHash: news.ContentHash(articleBody(article)),
+		Published:   n.Articles[i].PublishedTime,
+		Article:     &article,
+	}
+}
@@ -0,0 +1,42 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Stage identifies the step of generation that failed.
+type Stage string
+
+const (
+	StageCache    Stage = "cache"
+	StageSource   Stage = "source"
+	StageMessage  Stage = "message"
+	StageEncode   Stage = "encode"
+	StageCompress Stage = "compress"
+	StageSign     Stage = "sign"
+	StageOutput   Stage = "output"
+)
+
+var (
+	// ErrNoSigner is returned when Options.Signer is not set.
+	ErrNoSigner = errors.New("no signer configured")
+	// ErrInvalidArticle is returned when a source hands out an article that can't be written.
+	ErrInvalidArticle = errors.New("invalid article")
+	// ErrNotCached is returned by Replay when the cache has no articles to rebuild a file from.
+	ErrNotCached = errors.New("articles not cached")
+)
+
+// Error wraps a failure with the stage it happened in. Use errors.As to inspect the stage.
+type Error struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,186 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/wii-tools/lzx/lz10"
+)
+
+// fuzzArticles builds a slice of articles out of the fuzzer's inputs. Titles and contents are
+// split on "|" so a single input can describe several articles.
+func fuzzArticles(titles, contents, caption string, image []byte, topic int, flags uint8) []news.Article {
+	var articles []news.Article
+	bodies := strings.Split(contents, "|")
+
+	for i, title := range strings.Split(titles, "|") {
+		article := news.Article{
+			Title: title,
+			Topic: news.Topic(topic + i),
+		}
+
+		// Bit 0 leaves the content nil, bit 1 attaches the image and bit 2 drops the location.
+		if flags&1 == 0 {
+			content := bodies[i%len(bodies)]
+			article.Content = &content
+		}
+
+		if flags&2 != 0 {
+			article.Thumbnail = &news.Thumbnail{Image: image, Caption: caption}
+		}
+
+		if flags&4 == 0 {
+			article.Location = &news.Location{Name: news.SanJuanName}
+		}
+
+		articles = append(articles, article)
+		flags >>= 1
+	}
+
+	return articles
+}
+
+func FuzzMakeFile(f *testing.F) {
+	f.Add("Título", "Cuerpo del artículo", "Leyenda", []byte{0xFF, 0xD8}, 0, uint8(0))
+	f.Add("", "", "", []byte{}, 0, uint8(1))
+	f.Add("a|b|c|d|e|f|g|h|i|j|k|l", "x|y", "", []byte{1}, 0, uint8(0xFF))
+	f.Add("\xed\xa0\x80 unpaired surrogate", "emoji 🎤 and \x00 null", "\xed\xb0\x80", []byte{1, 2, 3}, 6, uint8(2))
+	f.Add("bad topic", "body", "", []byte(nil), 7, uint8(0))
+	f.Add("negative topic", "body", "", []byte(nil), -1, uint8(0))
+
+	f.Fuzz(func(t *testing.T, titles, contents, caption string, image []byte, topic int, flags uint8) {
+		articles := fuzzArticles(titles, contents, caption, image, topic, flags)
+
+		n := News{articles: articles, newsSource: &fuzzSource{}}
+		n.currentCountryCode = DefaultCountryCode
+		n.currentLanguageCode = DefaultLanguageCode
+		n.currentTime = goldenTime
+
+		payload, err := n.MakeFile()
+		if err != nil {
+			if !errors.Is(err, ErrInvalidArticle) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+
+		parsed, err := ParseFile(payload)
+		if err != nil {
+			t.Fatalf("generated file does not parse: %v", err)
+		}
+
+		checkInvariants(t, parsed, payload, articles)
+	})
+}
+
+// FuzzDecompressLZ10 checks that anything lz10.Compress produces decodes back without touching the
+// compressed data, and that arbitrary input never panics.
+func FuzzDecompressLZ10(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	// A back-reference in the first group sets the first flag byte, which lz10.Decompress clobbers.
+	f.Add([]byte{0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 2, 3, 1, 2, 3})
+	f.Add([]byte{0x10, 0xFF, 0xFF, 0xFF, 0x80, 0xF0, 0x00})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Arbitrary data is treated as a stream too; it only needs to fail cleanly.
+		decompressLZ10(data)
+
+		compressed, err := lz10.Compress(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		original := bytes.Clone(compressed)
+
+		decompressed, err := decompressLZ10(compressed)
+		if err != nil {
+			t.Fatalf("failed to decompress: %v", err)
+		}
+
+		if !bytes.Equal(decompressed, data) {
+			t.Errorf("round trip changed the data")
+		}
+		if !bytes.Equal(compressed, original) {
+			t.Errorf("decompressing modified its input")
+		}
+	})
+}
+
+func checkInvariants(t *testing.T, parsed *ParsedFile, payload []byte, articles []news.Article) {
+	t.Helper()
+	h := parsed.Header
+
+	if len(payload)%4 != 0 {
+		t.Errorf("file size %d is not 4-byte aligned", len(payload))
+	}
+
+	if int(h.NumberOfArticles) != len(articles) {
+		t.Fatalf("NumberOfArticles = %d, want %d", h.NumberOfArticles, len(articles))
+	}
+
+	if want := min(len(articles), 11); len(parsed.Headlines) != want {
+		t.Errorf("%d headlines, want %d", len(parsed.Headlines), want)
+	}
+
+	if len(parsed.Timestamps) != len(articles) {
+		t.Errorf("%d timestamps, want one per article (%d)", len(parsed.Timestamps), len(articles))
+	}
+
+	images := 0
+	for i, article := range articles {
+		got := parsed.Articles[i]
+
+		if want := roundTrip(article.Title); got.Title != want {
+			t.Errorf("article %d title = %q, want %q", i, got.Title, want)
+		}
+
+		body := ""
+		if article.Content != nil {
+			body = roundTrip(*article.Content)
+		}
+		if got.Body != body {
+			t.Errorf("article %d body = %q, want %q", i, got.Body, body)
+		}
+
+		if got.HeadlineOffset%4 != 0 || got.ArticleTextOffset%4 != 0 {
+			t.Errorf("article %d text is not 4-byte aligned", i)
+		}
+
+		hasImage := article.Thumbnail != nil && len(article.Thumbnail.Image) > 0
+		if !hasImage {
+			if got.PictureIndex != NoPicture {
+				t.Errorf("article %d has picture index %d but no image", i, got.PictureIndex)
+			}
+			continue
+		}
+
+		image := parsed.Images[got.PictureIndex]
+		picture := payload[image.PictureOffset : image.PictureOffset+image.PictureSize]
+		if !bytes.Equal(picture, article.Thumbnail.Image) {
+			t.Errorf("article %d image does not round trip", i)
+		}
+
+		if want := uint32(len(encodeText(article.Thumbnail.Caption)) * 2); image.CaptionSize != want {
+			t.Errorf("article %d caption size = %d, want %d", i, image.CaptionSize, want)
+		}
+		images++
+	}
+
+	if int(h.NumberOfImages) != images {
+		t.Errorf("NumberOfImages = %d, want %d", h.NumberOfImages, images)
+	}
+}
+
+// roundTrip is what a string looks like after being written and read back.
+func roundTrip(text string) string {
+	return string(utf16.Decode(encodeText(text)))
+}
+
+type fuzzSource struct{}
+
+func (fuzzSource) GetArticles() ([]news.Article, error) { return nil, nil }
+func (fuzzSource) GetLogo() []byte                      { return testLogo }
@@ -0,0 +1,405 @@
+package generator
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/filter"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/endi"
+	"WiiNewsPR/signing"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"time"
+
+	"github.com/wii-tools/lzx/lz10"
+)
+
+const (
+	// DefaultLanguageCode is English.
+	DefaultLanguageCode = 1
+	// DefaultCountryCode is the USA.
+	DefaultCountryCode = 49
+)
+
+type News struct {
+	Header          Header
+	Headlines       []Headlines
+	HeadlineText    []uint16
+	Topics          []Topic
+	Timestamps      []Timestamp
+	TopicText       []uint16
+	Articles        []Article
+	ArticleText     []uint16
+	Sources         []Source
+	SourcePictures  []byte
+	SourceCopyright []byte
+	Locations       []Location
+	LocationText    []uint16
+	Images          []Image
+	ImagesData      []byte
+	CaptionData     []uint16
+	MessageText     []uint16
+
+	newsSource news.Source
+	// The urgent message shown above the articles, if any.
+	message *news.Message
+
+	currentLanguageCode uint8
+	currentCountryCode  uint8
+	// The local hours the file is for, one per distinct hour of the target time zones.
+	hours []int
+
+	// The time the file is generated for, in UTC. Every timestamp in the file and cache is
+	// derived from it.
+	currentTime time.Time
+
+	cacheStore cache.Store
+	cache      *cache.File
+	// How far back cached slots are used for timestamps and duplicate titles.
+	retention time.Duration
+
+	// The cached articles of previous hours, which new articles are deduplicated against.
+	pastEntries []cache.Entry
+	// The stories of previous hours that articles of this hour update, by article index.
+	updates map[int]cache.Entry
+	// The articles dropped as duplicates.
+	merges []Merge
+	// What the content rules did to the articles.
+	filtered []filter.Change
+
+	// Placeholder for the timestamps for a specific topic.
+	timestamps [][]Timestamp
+
+	articles []news.Article
+	// The distinct places of the articles, in the order of the location table.
+	places []news.Location
+
+	// Placeholder for the topics.
+	topics []Topic
+}
+
+// Options configures a single generation run.
+type Options struct {
+	// CacheDir holds the articles used in previous hours.
+	CacheDir string
+	// CacheStore replaces CacheDir with another backend, such as object storage.
+	CacheStore cache.Store
+	// LockTimeout is how long to wait for another generation using the same cache to finish.
+	// Zero fails straight away with cache.ErrLocked.
+	LockTimeout time.Duration
+	// Retention is how far back cached articles are included. Defaults to 24 hours.
+	Retention time.Duration
+	// Signer signs the compressed file. It is required.
+	Signer signing.Signer
+	// Source provides the articles. Defaults to El Nuevo Día.
+	Source news.Source
+	// LanguageCode and CountryCode default to English and the USA.
+	LanguageCode uint8
+	CountryCode  uint8
+	// Clock provides the time the file is generated for. Defaults to the system clock.
+	Clock Clock
+	// TimeZones are the zones of the consoles the file is for. The Wii requests news.bin.HH for
+	// the hour it is locally, so the same file is written for each distinct local hour. Defaults
+	// to the zone of the clock's time. The timestamps inside the file are always UTC.
+	TimeZones []*time.Location
+	// Dedup tunes duplicate detection across sources and previous hours.
+	Dedup DedupOptions
+	// Output receives the file once it is signed. When nil the file is only returned.
+	Output OutputSink
+	// Message provides an urgent message for the top of the channel. It is left out once it
+	// expires. When nil there is no message.
+	Message news.MessageSource
+	// Filter is applied to the articles from Source before they are deduplicated and selected.
+	Filter filter.Rules
+}
+
+func (o *Options) setDefaults() {
+	if o.LanguageCode == 0 {
+		o.LanguageCode = DefaultLanguageCode
+	}
+
+	if o.CountryCode == 0 {
+		o.CountryCode = DefaultCountryCode
+	}
+
+	if o.Clock == nil {
+		o.Clock = SystemClock{}
+	}
+
+	if o.Retention <= 0 {
+		o.Retention = cache.DefaultRetention
+	}
+
+	if o.CacheStore == nil {
+		o.CacheStore = cache.NewDirStore(o.CacheDir)
+	}
+}
+
+// Result is a generated news file.
+type Result struct {
+	LanguageCode uint8
+	CountryCode  uint8
+	// Hour is the local hour of the first time zone.
+	Hour int
+	// Hours are the local hours of every time zone, without repeats, starting with Hour.
+	Hours []int
+	// Data is the compressed and signed file.
+	Data []byte
+	// NumberOfArticles is the amount of articles written for this hour.
+	NumberOfArticles int
+	// Message is the urgent message the file carries, if any.
+	Message *news.Message
+	// Merges lists the articles dropped as duplicates, for debugging.
+	Merges []Merge
+	// Filtered lists every article a content rule dropped or changed.
+	Filtered []filter.Change
+	// Location is where Options.Output stored the file for Hour, such as a path or URL. It is
+	// empty without an output.
+	Location string
+	// Locations are where the file was stored for each of Hours.
+	Locations []string
+}
+
+// Path returns the path of the file relative to the output root, as requested by the Wii.
+func (r Result) Path() string {
+	return r.path(r.Hour)
+}
+
+// Paths returns the path of the file for each of Hours.
+func (r Result) Paths() []string {
+	if len(r.Hours) == 0 {
+		return []string{r.Path()}
+	}
+
+	paths := make([]string, len(r.Hours))
+	for i, hour := range r.Hours {
+		paths[i] = r.path(hour)
+	}
+	return paths
+}
+
+func (r Result) path(hour int) string {
+	return fmt.Sprintf("v2/%d/%03d/news.bin.%02d", r.LanguageCode, r.CountryCode, hour)
+}
+
+// Generate builds, compresses and signs the news file for the current hour of opts.Clock, and writes
+// it to opts.Output.
+func Generate(ctx context.Context, opts Options) (Result, error) {
+	if opts.Signer == nil {
+		return Result{}, &Error{Stage: StageSign, Err: ErrNoSigner}
+	}
+
+	opts.setDefaults()
+
+	// The cache is read at the start and written at the end, so overlapping runs would lose
+	// each other's articles. Hold the lock for the whole run.
+	unlock, err := cache.Acquire(ctx, opts.CacheStore, opts.LockTimeout)
+	if err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	// The file is out by the time the lock is released. A lock that can't be released only holds
+	// up the next run until it expires, so it doesn't turn a published file into a failure.
+	result, err := generate(ctx, opts)
+	if unlockErr := unlock(); unlockErr != nil {
+		log.Printf("Warning: Failed to release the cache lock: %v\n", unlockErr)
+	}
+
+	return result, err
+}
+
+func generate(ctx context.Context, opts Options) (Result, error) {
+	n := News{}
+	n.retention = opts.Retention
+	n.currentCountryCode = opts.CountryCode
+	n.currentLanguageCode = opts.LanguageCode
+
+	now := opts.Clock.Now()
+	n.currentTime = now.UTC()
+	n.hours = localHours(now, opts.TimeZones)
+
+	if err := n.ReadNewsCache(ctx, opts.CacheStore); err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	n.newsSource = opts.Source
+	if n.newsSource == nil {
+		n.newsSource = endi.NewEndi()
+	}
+
+	if err := n.GetNewsArticles(); err != nil {
+		return Result{}, &Error{Stage: StageSource, Err: err}
+	}
+
+	if err := n.GetMessage(opts.Message); err != nil {
+		return Result{}, &Error{Stage: StageMessage, Err: err}
+	}
+
+	n.articles, n.filtered = opts.Filter.Apply(n.articles)
+	n.pinFirst()
+	n.deduplicate(opts.Dedup)
+
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+
+	payload, err := n.MakeFile()
+	if err != nil {
+		return Result{}, &Error{Stage: StageEncode, Err: err}
+	}
+
+	result, err := n.pack(ctx, opts.Signer, payload)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if result, err = output(ctx, opts.Output, result); err != nil {
+		return Result{}, err
+	}
+
+	// The articles are only recorded once the file is out. Otherwise the next run would take them
+	// for already published, and drop them as duplicates.
+	if err = n.WriteNewsCache(ctx); err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	return result, nil
+}
+
+// output hands the file to the sink for each of its hours, if there is a sink, and records where
+// it went.
+func output(ctx context.Context, sink OutputSink, result Result) (Result, error) {
+	if sink == nil {
+		return result, nil
+	}
+
+	for _, path := range result.Paths() {
+		location, err := sink.Write(ctx, path, result.Data)
+		if err != nil {
+			return Result{}, &Error{Stage: StageOutput, Err: err}
+		}
+		result.Locations = append(result.Locations, location)
+	}
+
+	result.Location = result.Locations[0]
+	return result, nil
+}
+
+// pack compresses and signs the file built from payload.
+func (n *News) pack(ctx context.Context, signer signing.Signer, payload []byte) (Result, error) {
+	compressed, err := lz10.Compress(payload)
+	if err != nil {
+		return Result{}, &Error{Stage: StageCompress, Err: err}
+	}
+
+	signed, err := signing.SignFile(ctx, signer, compressed)
+	if err != nil {
+		return Result{}, &Error{Stage: StageSign, Err: err}
+	}
+
+	return Result{
+		LanguageCode:     n.currentLanguageCode,
+		CountryCode:      n.currentCountryCode,
+		Hour:             n.hours[0],
+		Hours:            n.hours,
+		Data:             signed,
+		NumberOfArticles: len(n.Articles),
+		Message:          n.message,
+		Merges:           n.merges,
+		Filtered:         n.filtered,
+	}, nil
+}
+
+// MakeFile validates the articles and builds every table, returning the uncompressed file.
+func (n *News) MakeFile() ([]byte, error) {
+	if err := validateArticles(n.articles); err != nil {
+		return nil, err
+	}
+
+	if n.timestamps == nil {
+		n.timestamps = make([][]Timestamp, len(topics)+1)
+		n.topics = make([]Topic, len(topics)+1)
+	}
+
+	n.MakeHeader()
+	n.MakeWiiMenuHeadlines()
+	n.MakeArticleTable()
+	n.MakeTopicTable()
+	n.MakeSourceTable()
+	n.MakeLocationTable()
+	n.WriteImages()
+	n.MakeMessage()
+	n.Header.Filesize = n.GetCurrentSize()
+
+	buffer := new(bytes.Buffer)
+	if err := n.WriteAll(buffer); err != nil {
+		return nil, err
+	}
+
+	crcTable := crc32.MakeTable(crc32.IEEE)
+	n.Header.CRC32 = crc32.Checksum(buffer.Bytes()[12:], crcTable)
+
+	buffer.Reset()
+	if err := n.WriteAll(buffer); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// validateArticles rejects articles the tables can't represent.
+func validateArticles(articles []news.Article) error {
+	for i, article := range articles {
+		if article.Topic < 0 || int(article.Topic) >= len(topics) {
+			return fmt.Errorf("%w: article %d has unknown topic %d", ErrInvalidArticle, i, article.Topic)
+		}
+	}
+
+	return nil
+}
+
+// sections returns every table in the order it is laid out in the file.
+func (n *News) sections() []any {
+	return []any{
+		n.Header,
+		n.Headlines,
+		n.HeadlineText,
+		n.Articles,
+		n.ArticleText,
+		n.Topics,
+		n.Timestamps,
+		n.TopicText,
+		n.Sources,
+		n.SourcePictures,
+		n.Locations,
+		n.LocationText,
+		n.Images,
+		n.ImagesData,
+		n.CaptionData,
+		n.MessageText,
+	}
+}
+
+func (n *News) WriteAll(writer io.Writer) error {
+	for _, section := range n.sections() {
+		if err := binary.Write(writer, binary.BigEndian, section); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetCurrentSize returns the size the file would have if it was written now.
+func (n *News) GetCurrentSize() uint32 {
+	size := 0
+	for _, section := range n.sections() {
+		size += binary.Size(section)
+	}
+
+	return uint32(size)
+}
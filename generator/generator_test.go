@@ -0,0 +1,664 @@
+package generator
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/filter"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/signing"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+// goldenTime is 2024-09-01 14:30 in Puerto Rico.
+var goldenTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+var testLogo = bytes.Repeat([]byte{0xAB}, 10)
+
+var goldenCases = []struct {
+	name string
+	// fixtures are generated one hour apart, sharing a cache. The golden file is the last one.
+	fixtures []string
+}{
+	{name: "empty", fixtures: []string{"empty"}},
+	{name: "basic", fixtures: []string{"basic"}},
+	{name: "images", fixtures: []string{"images"}},
+	{name: "two_hours", fixtures: []string{"basic", "images"}},
+}
+
+func testSigner(t testing.TB) *signing.PEMSigner {
+	t.Helper()
+
+	signer, err := signing.NewPEMSigner(filepath.Join("testdata", "test_key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer
+}
+
+// generateFixture generates the file for a fixture and returns the decompressed payload.
+func generateFixture(t testing.TB, signer *signing.PEMSigner, fixture, cacheDir string, at time.Time) []byte {
+	t.Helper()
+
+	articles, err := newstest.LoadArticles(filepath.Join("testdata", "fixtures", fixture+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+		Clock:    FixedClock(at),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = signing.VerifyFile(result.Data, signer.PublicKey()); err != nil {
+		t.Fatalf("generated file does not verify: %v", err)
+	}
+
+	payload, err := decompressLZ10(result.Data[64+signing.SignatureSize:])
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+
+	return payload
+}
+
+func TestGenerateGolden(t *testing.T) {
+	signer := testSigner(t)
+
+	for _, tc := range goldenCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cacheDir := t.TempDir()
+
+			var payload []byte
+			for i, fixture := range tc.fixtures {
+				at := goldenTime.Add(time.Duration(i-len(tc.fixtures)+1) * time.Hour)
+				payload = generateFixture(t, signer, fixture, cacheDir, at)
+			}
+
+			goldenPath := filepath.Join("testdata", "golden", tc.name+".bin")
+			if *update {
+				if err := os.WriteFile(goldenPath, payload, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			golden, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("missing golden file, run with -update: %v", err)
+			}
+
+			if !bytes.Equal(payload, golden) {
+				t.Errorf("payload differs from %s (got %d bytes, want %d); run with -update if the change is intended", goldenPath, len(payload), len(golden))
+			}
+		})
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	signer := testSigner(t)
+
+	first := generateFixture(t, signer, "images", t.TempDir(), goldenTime)
+	second := generateFixture(t, signer, "images", t.TempDir(), goldenTime)
+
+	if !bytes.Equal(first, second) {
+		t.Error("same inputs produced different payloads")
+	}
+}
+
+func TestGenerateHeader(t *testing.T) {
+	signer := testSigner(t)
+	headerSize := uint32(binary.Size(Header{}))
+
+	tests := []struct {
+		fixture   string
+		articles  uint32
+		headlines uint32
+		images    uint32
+	}{
+		{fixture: "empty", articles: 0, headlines: 0, images: 0},
+		{fixture: "basic", articles: 6, headlines: 6, images: 0},
+		{fixture: "images", articles: 3, headlines: 3, images: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			payload := generateFixture(t, signer, tt.fixture, t.TempDir(), goldenTime)
+
+			var header Header
+			if err := binary.Read(bytes.NewReader(payload), binary.BigEndian, &header); err != nil {
+				t.Fatal(err)
+			}
+
+			if header.Filesize != uint32(len(payload)) {
+				t.Errorf("Filesize = %d, want %d", header.Filesize, len(payload))
+			}
+
+			if crc := crc32.ChecksumIEEE(payload[12:]); header.CRC32 != crc {
+				t.Errorf("CRC32 = %08x, want %08x", header.CRC32, crc)
+			}
+
+			if header.UpdatedTimestamp != fixTime(goldenTime) || header.EndTimestamp != fixTime(goldenTime)+1500 {
+				t.Errorf("timestamps = %d..%d, want %d..%d", header.UpdatedTimestamp, header.EndTimestamp, fixTime(goldenTime), fixTime(goldenTime)+1500)
+			}
+
+			counts := []struct {
+				name      string
+				got, want uint32
+			}{
+				{"NumberOfArticles", header.NumberOfArticles, tt.articles},
+				{"NumberOfHeadlines", header.NumberOfHeadlines, tt.headlines},
+				{"NumberOfImages", header.NumberOfImages, tt.images},
+				{"NumberOfTopics", header.NumberOfTopics, uint32(len(topics) + 1)},
+				{"NumberOfSources", header.NumberOfSources, 1},
+				{"NumberOfLocations", header.NumberOfLocations, 1},
+			}
+			for _, count := range counts {
+				if count.got != count.want {
+					t.Errorf("%s = %d, want %d", count.name, count.got, count.want)
+				}
+			}
+
+			if header.HeadlinesTableOffset != headerSize {
+				t.Errorf("HeadlinesTableOffset = %d, want %d", header.HeadlinesTableOffset, headerSize)
+			}
+
+			// The tables are written in this order, so their offsets must never decrease.
+			offsets := []struct {
+				name   string
+				offset uint32
+			}{
+				{"HeadlinesTableOffset", header.HeadlinesTableOffset},
+				{"ArticleTableOffset", header.ArticleTableOffset},
+				{"TopicTableOffset", header.TopicTableOffset},
+				{"SourceTableOffset", header.SourceTableOffset},
+				{"LocationTableOffset", header.LocationTableOffset},
+				{"ImagesTableOffset", header.ImagesTableOffset},
+			}
+			previous := uint32(0)
+			for _, o := range offsets {
+				if o.offset < previous || o.offset > header.Filesize {
+					t.Errorf("%s = %d is out of order (previous %d, filesize %d)", o.name, o.offset, previous, header.Filesize)
+				}
+				if o.offset%4 != 0 {
+					t.Errorf("%s = %d is not 4-byte aligned", o.name, o.offset)
+				}
+				previous = o.offset
+			}
+		})
+	}
+}
+
+func TestGenerateCacheRetention(t *testing.T) {
+	signer := testSigner(t)
+
+	tests := []struct {
+		retention  time.Duration
+		timestamps int
+	}{
+		// The basic fixture was generated 30 hours earlier, so only the current 3 articles count.
+		{retention: 0, timestamps: 3},
+		{retention: 48 * time.Hour, timestamps: 3 + 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.retention.String(), func(t *testing.T) {
+			cacheDir := t.TempDir()
+			generateFixture(t, signer, "basic", cacheDir, goldenTime.Add(-30*time.Hour))
+
+			articles, err := newstest.LoadArticles(filepath.Join("testdata", "fixtures", "images.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := Generate(context.Background(), Options{
+				CacheDir:  cacheDir,
+				Retention: tt.retention,
+				Signer:    signer,
+				Source:    &newstest.Source{Articles: articles, Logo: testLogo},
+				Clock:     FixedClock(goldenTime),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := DecodeFile(result.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(parsed.Timestamps) != tt.timestamps {
+				t.Errorf("%d timestamps, want %d", len(parsed.Timestamps), tt.timestamps)
+			}
+		})
+	}
+}
+
+func TestGenerateLocked(t *testing.T) {
+	cacheDir := t.TempDir()
+	unlock, err := cache.NewDirStore(cacheDir).Lock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	_, err = Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+	})
+
+	var genErr *Error
+	if !errors.Is(err, cache.ErrLocked) || !errors.As(err, &genErr) || genErr.Stage != StageCache {
+		t.Fatalf("err = %v, want a cache stage ErrLocked", err)
+	}
+}
+
+// stuckLockStore can't release its lock.
+type stuckLockStore struct {
+	cache.Store
+}
+
+func (s stuckLockStore) Lock(ctx context.Context) (cache.Unlock, error) {
+	if _, err := s.Store.Lock(ctx); err != nil {
+		return nil, err
+	}
+	return func() error { return errors.New("lock object is gone") }, nil
+}
+
+func TestGenerateUnlockFails(t *testing.T) {
+	result, err := Generate(context.Background(), Options{
+		CacheStore: stuckLockStore{cache.NewDirStore(t.TempDir())},
+		Signer:     testSigner(t),
+		Source:     &newstest.Source{Logo: testLogo},
+		Clock:      FixedClock(goldenTime),
+	})
+
+	// The file was built, so the run succeeds.
+	if err != nil || len(result.Data) == 0 {
+		t.Fatalf("Generate = %d bytes, %v", len(result.Data), err)
+	}
+}
+
+func TestGenerateDeduplicates(t *testing.T) {
+	signer := testSigner(t)
+	cacheDir := t.TempDir()
+	text := func(s string) *string { return &s }
+
+	budget := "El presupuesto de 13,100 millones entra en vigor el 1 de julio. La Legislatura lo aprobó la semana pasada."
+	previous := []news.Article{{Title: "Gobernadora firma ley de presupuesto", Content: text(budget), Topic: news.NationalNews}}
+	if _, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Articles: previous, Logo: testLogo},
+		Clock:    FixedClock(goldenTime.Add(-time.Hour)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	rain := "Las lluvias regresan al área metropolitana con inundaciones en Río Piedras y Santurce durante la tarde del domingo."
+	articles := []news.Article{
+		{Title: "ACTUALIZADO: Gobernadora firma la ley de presupuesto", Content: text(budget), Topic: news.NationalNews},
+		{Title: "Vuelven las lluvias", Content: text(rain), Topic: news.NationalNews},
+		// Another source's version of the same story, with the picture the first one lacks.
+		{Title: "Inundaciones en el área metro", Content: text(rain + " Se esperan más."), Topic: news.Science, Thumbnail: &news.Thumbnail{Image: []byte{0xFF, 0xD8}, Caption: "Río Piedras"}},
+		{Title: "Cangrejeros ganan el primer juego", Content: text("Santurce se impuso ante Bayamón en el Coliseo."), Topic: news.Sports},
+	}
+
+	result, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Merges) != 2 {
+		t.Fatalf("merges = %v, want 2", result.Merges)
+	}
+	if m := result.Merges[0]; !m.Past || m.Reason != "title" || m.Into != previous[0].Title {
+		t.Errorf("first merge = %s", m)
+	}
+	if m := result.Merges[1]; m.Past || m.Reason != "body" || m.Into != "Vuelven las lluvias" {
+		t.Errorf("second merge = %s", m)
+	}
+
+	parsed, err := DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed.Articles) != 2 || parsed.Articles[0].Title != "Vuelven las lluvias" {
+		t.Fatalf("articles = %+v", parsed.Articles)
+	}
+	if len(parsed.Images) != 1 {
+		t.Errorf("the picture of the merged duplicate was not kept: %d images", len(parsed.Images))
+	}
+}
+
+func TestGenerateStoryUpdates(t *testing.T) {
+	signer := testSigner(t)
+	cacheDir := t.TempDir()
+	text := func(s string) *string { return &s }
+
+	generate := func(at time.Time, articles ...news.Article) (Result, *ParsedFile) {
+		t.Helper()
+
+		result, err := Generate(context.Background(), Options{
+			CacheDir: cacheDir,
+			Signer:   signer,
+			Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+			Clock:    FixedClock(at),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := DecodeFile(result.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result, parsed
+	}
+
+	first, second, third := goldenTime.Add(-2*time.Hour), goldenTime.Add(-time.Hour), goldenTime
+	storm := news.Article{Title: "Tormenta tropical se acerca a la isla", Content: text("La tormenta se encuentra a 300 millas al sureste de Puerto Rico."), Topic: news.NationalNews}
+	game := news.Article{Title: "Cangrejeros ganan el primer juego", Content: text("Santurce se impuso ante Bayamón en el Coliseo."), Topic: news.Sports}
+	generate(first, storm, game)
+
+	// The storm story changes materially, so it replaces the version from the first hour.
+	updated := news.Article{Title: "ACTUALIZADO: Tormenta tropical se acerca a la isla", Content: text("La tormenta se convirtió en huracán categoría 1 y se encuentra a 150 millas de Puerto Rico."), Topic: news.NationalNews}
+	school := news.Article{Title: "Escuelas cierran el lunes", Content: text("El Departamento de Educación suspendió las clases por el huracán."), Topic: news.NationalNews}
+	result, parsed := generate(second, updated, game, school)
+
+	if len(result.Merges) != 2 || !result.Merges[0].Updated || result.Merges[1].Updated {
+		t.Fatalf("merges = %v, want the storm updated and the game dropped", result.Merges)
+	}
+
+	if len(parsed.Articles) != 2 {
+		t.Fatalf("%d articles, want 2", len(parsed.Articles))
+	}
+	storm2, school2 := parsed.Articles[0], parsed.Articles[1]
+	if storm2.ID != 1 || storm2.PublishedTime != fixTime(first) || storm2.UpdatedTime != fixTime(second) {
+		t.Errorf("update = ID %d published %d updated %d, want ID 1 published %d updated %d", storm2.ID, storm2.PublishedTime, storm2.UpdatedTime, fixTime(first), fixTime(second))
+	}
+	if school2.ID != 2 {
+		t.Errorf("new article ID = %d, want 2 as 1 is taken by the update", school2.ID)
+	}
+
+	// The first hour's storm is gone from the timestamps; its game stays.
+	want := map[Timestamp]bool{
+		{Time: fixTime(first), ArticleNumber: 2}:  true,
+		{Time: fixTime(second), ArticleNumber: 1}: true,
+		{Time: fixTime(second), ArticleNumber: 2}: true,
+	}
+	if len(parsed.Timestamps) != len(want) {
+		t.Fatalf("timestamps = %+v", parsed.Timestamps)
+	}
+	for _, timestamp := range parsed.Timestamps {
+		if !want[timestamp] {
+			t.Errorf("unexpected timestamp %+v", timestamp)
+		}
+	}
+
+	// Seeing the same version again is a plain duplicate, and only the latest version is listed.
+	result, parsed = generate(third, updated)
+	if len(result.Merges) != 1 || result.Merges[0].Updated || len(parsed.Articles) != 0 {
+		t.Fatalf("merges = %v, %d articles", result.Merges, len(parsed.Articles))
+	}
+	if len(parsed.Timestamps) != len(want) {
+		t.Errorf("timestamps = %+v, want the latest version of each story", parsed.Timestamps)
+	}
+}
+
+func TestGenerateMinorEditNotUpdate(t *testing.T) {
+	signer := testSigner(t)
+	cacheDir := t.TempDir()
+	text := func(s string) *string { return &s }
+
+	body := "La tormenta se encuentra a 300 millas al sureste de Puerto Rico y se mueve hacia el oeste a 15 millas por hora. El Servicio Nacional de Meteorología recomienda a los residentes prepararse."
+	storm := news.Article{Title: "Tormenta tropical se acerca a la isla", Content: text(body), Topic: news.NationalNews}
+	if _, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Articles: []news.Article{storm}, Logo: testLogo},
+		Clock:    FixedClock(goldenTime.Add(-time.Hour)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A corrected word is the same story, so it is dropped rather than re-emitted as an update.
+	edited := storm
+	edited.Content = text(strings.Replace(body, "recomienda", "aconseja", 1))
+	result, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Articles: []news.Article{edited}, Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.NumberOfArticles != 0 || len(result.Merges) != 1 || result.Merges[0].Updated {
+		t.Errorf("%d articles, merges = %v, want the edit dropped as a duplicate", result.NumberOfArticles, result.Merges)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	signer := testSigner(t)
+	cacheDir := t.TempDir()
+	text := func(s string) *string { return &s }
+
+	generate := func(at time.Time, articles ...news.Article) Result {
+		t.Helper()
+
+		result, err := Generate(context.Background(), Options{
+			CacheDir: cacheDir,
+			Signer:   signer,
+			Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+			Clock:    FixedClock(at),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	first, second := goldenTime.Add(-time.Hour), goldenTime
+	storm := news.Article{Title: "Tormenta tropical se acerca a la isla", Content: text("La tormenta se encuentra a 300 millas al sureste de Puerto Rico."), Topic: news.NationalNews}
+	updated := news.Article{Title: "ACTUALIZADO: Tormenta tropical se acerca a la isla", Content: text("La tormenta se convirtió en huracán categoría 1 y se encuentra a 150 millas de Puerto Rico."), Topic: news.NationalNews}
+	school := news.Article{Title: "Escuelas cierran el lunes", Content: text("El Departamento de Educación suspendió las clases por el huracán."), Topic: news.NationalNews, Thumbnail: &news.Thumbnail{Image: []byte{0xFF, 0xD8}, Caption: "Escuela"}}
+	generate(first, storm)
+	original := generate(second, updated, school)
+	generate(second.Add(time.Hour), school)
+
+	replayed, err := Replay(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   signer,
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(second.Add(3 * time.Hour)),
+	}, second.Add(10*time.Minute), second.Hour())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replayed.Path() != original.Path() || !bytes.Equal(replayed.Data, original.Data) {
+		t.Errorf("replayed %s differs from the original %s", replayed.Path(), original.Path())
+	}
+
+	_, err = Replay(context.Background(), Options{CacheDir: cacheDir, Signer: signer}, second.Add(-5*time.Hour), 13)
+	if !errors.Is(err, ErrNotCached) {
+		t.Errorf("err = %v, want ErrNotCached for an hour without a slot", err)
+	}
+
+	// A generation holding the cache is waited for like by Generate.
+	unlock, err := cache.NewDirStore(cacheDir).Lock(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unlock()
+
+	_, err = Replay(context.Background(), Options{CacheDir: cacheDir, Signer: signer}, second.Add(10*time.Minute), second.Hour())
+	if !errors.Is(err, cache.ErrLocked) {
+		t.Errorf("err = %v, want ErrLocked while the cache is locked", err)
+	}
+}
+
+// memorySink keeps written files, or fails every write with err.
+type memorySink struct {
+	files map[string][]byte
+	err   error
+}
+
+func (s *memorySink) Write(_ context.Context, key string, data []byte) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	s.files[key] = data
+	return "memory:" + key, nil
+}
+
+func TestGenerateOutput(t *testing.T) {
+	output := &memorySink{files: map[string][]byte{}}
+	result, err := Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Output:   output,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Location != "memory:v2/1/049/news.bin.18" || !bytes.Equal(output.files[result.Path()], result.Data) {
+		t.Errorf("location = %q, files = %d", result.Location, len(output.files))
+	}
+
+	_, err = Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Output:   &memorySink{err: errors.New("disk full")},
+	})
+
+	var genErr *Error
+	if !errors.As(err, &genErr) || genErr.Stage != StageOutput {
+		t.Errorf("err = %v, want an output stage error", err)
+	}
+}
+
+func TestGenerateFailedOutputNotCached(t *testing.T) {
+	cacheDir := t.TempDir()
+	articles := []news.Article{{Title: "Cangrejeros ganan el primer juego de la final", Topic: news.Sports}}
+	opts := Options{
+		CacheDir: cacheDir,
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+		Clock:    FixedClock(goldenTime.Add(-time.Hour)),
+		Output:   &memorySink{err: errors.New("disk full")},
+	}
+	if _, err := Generate(context.Background(), opts); err == nil {
+		t.Fatal("failed output was reported as a success")
+	}
+
+	// The article never reached the consoles, so it isn't a duplicate of itself next hour.
+	opts.Clock = FixedClock(goldenTime)
+	opts.Output = nil
+	result, err := Generate(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.NumberOfArticles != 1 || len(result.Merges) != 0 {
+		t.Errorf("%d articles, merges = %v", result.NumberOfArticles, result.Merges)
+	}
+}
+
+func TestGeneratePinned(t *testing.T) {
+	text := func(s string) *string { return &s }
+	feed := news.Article{Title: "AAA restablece el servicio en Mayagüez", Content: text("La AAA restableció el servicio a 1,200 clientes en Mayagüez."), Topic: news.NationalNews}
+	other := news.Article{Title: "Cangrejeros ganan el primer juego de la final", Topic: news.Sports}
+	correction := news.Article{Title: "AAA restablece el servicio en Mayagüez", Content: text("La AAA restableció el servicio a 12,000 clientes en Mayagüez."), Topic: news.NationalNews, Pinned: true}
+
+	result, err := Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Articles: []news.Article{feed, other, correction}, Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pinned correction comes first and the feed's version of the story is dropped.
+	if len(parsed.Articles) != 2 || parsed.Articles[0].Body != *correction.Content || parsed.Articles[1].Title != other.Title {
+		t.Errorf("articles = %+v", parsed.Articles)
+	}
+}
+
+func TestGenerateFilter(t *testing.T) {
+	rules, err := filter.Parse([]byte(`[
+		{"name": "horoscope", "match": {"title": "(?i)^horóscopo"}, "action": "drop"},
+		{"name": "sponsored", "match": {"category": "Contenido auspiciado"}, "action": "demote"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	articles := []news.Article{
+		{Title: "Tienda abre en Plaza Las Américas", Topic: news.Business, Categories: []string{"Contenido auspiciado"}},
+		{Title: "Horóscopo del día", Topic: news.Entertainment},
+		{Title: "Cangrejeros ganan el primer juego de la final", Topic: news.Sports},
+	}
+
+	result, err := Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Filter:   rules,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The horoscope is dropped and the sponsored post comes after the others.
+	if len(parsed.Articles) != 2 || parsed.Articles[0].Title != articles[2].Title || parsed.Articles[1].Title != articles[0].Title {
+		t.Errorf("articles = %+v", parsed.Articles)
+	}
+
+	if len(result.Filtered) != 2 || result.Filtered[0].Rule != "sponsored" || result.Filtered[1].Rule != "horoscope" {
+		t.Errorf("filtered = %v", result.Filtered)
+	}
+}
@@ -1,4 +1,9 @@
-package main
+package generator
+
+import "time"
+
+// Lifetime is how long the Wii keeps using a file after the time it was generated for.
+const Lifetime = 25 * time.Hour
 
 type Header struct {
 	Version          uint32
@@ -38,10 +43,10 @@ func (n *News) MakeHeader() {
 		Version:                  512,
 		Filesize:                 0,
 		CRC32:                    0,
-		UpdatedTimestamp:         fixTime(currentTime),
-		EndTimestamp:             fixTime(currentTime) + 1500,
+		UpdatedTimestamp:         fixTime(n.currentTime),
+		EndTimestamp:             fixTime(n.currentTime) + uint32(Lifetime/time.Minute),
 		CountryCode:              n.currentCountryCode,
-		UpdatedTimestamp2:        fixTime(currentTime),
+		UpdatedTimestamp2:        fixTime(n.currentTime),
 		SupportedLanguages:       [16]uint8{1, 3, 4, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
 		LanguageCode:             n.currentLanguageCode,
 		GooFlag:                  0,
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"sort"
+)
+
+// MaxHeadlines is how many headlines the Wii Menu ticker shows.
+const MaxHeadlines = 11
+
+// Headlines are the news articles that will appear on the News Channel banner in the Wii Menu.
+type Headlines struct {
+	HeadlineSize   uint32
+	HeadlineOffset uint32
+}
+
+// selectHeadlines picks the articles for the Wii Menu, by index: the pinned ones first, then one
+// article of each topic in turn, the most recent of each first, so no topic crowds out the others.
+// Demoted articles only fill what is left.
+func selectHeadlines(articles []news.Article, limit int) []int {
+	var selected, demoted []int
+	byTopic := map[news.Topic][]int{}
+	var order []news.Topic
+	for i, article := range articles {
+		if article.Pinned {
+			selected = append(selected, i)
+			continue
+		}
+
+		if article.Demoted {
+			demoted = append(demoted, i)
+			continue
+		}
+
+		if _, ok := byTopic[article.Topic]; !ok {
+			order = append(order, article.Topic)
+		}
+		byTopic[article.Topic] = append(byTopic[article.Topic], i)
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+		return order[i] < order[j]
+	})
+
+	// Articles without a publication time keep the order of their source, after the dated ones.
+	for _, topic := range order {
+		indexes := byTopic[topic]
+		sort.SliceStable(indexes, func(i, j int) bool {
+			return articles[indexes[i]].Published.After(articles[indexes[j]].Published)
+		})
+	}
+
+	for round := 0; len(selected)+len(demoted) < len(articles); round++ {
+		for _, topic := range order {
+			if round < len(byTopic[topic]) {
+				selected = append(selected, byTopic[topic][round])
+			}
+		}
+	}
+
+	selected = append(selected, demoted...)
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+	return selected
+}
+
+func (n *News) MakeWiiMenuHeadlines() {
+	n.Header.HeadlinesTableOffset = n.GetCurrentSize()
+
+	selected := selectHeadlines(n.articles, MaxHeadlines)
+	n.Headlines = make([]Headlines, len(selected))
+
+	for i, index := range selected {
+		article := n.articles[index]
+
+		headline := article.MenuHeadline
+		if headline == "" {
+			headline = article.Title
+		}
+
+		// Encode to UTF-16
+		encoded := encodeText(headline)
+
+		n.Headlines[i] = Headlines{
+			HeadlineSize:   uint32(len(encoded)) * 2,
+			HeadlineOffset: n.GetCurrentSize(),
+		}
+
+		n.HeadlineText = append(n.HeadlineText, encoded...)
+
+		// Padding time.
+		if (n.GetCurrentSize()+2)%4 == 0 {
+			n.HeadlineText = append(n.HeadlineText, uint16(0))
+		} else if (n.GetCurrentSize()+4)%4 == 0 {
+			n.HeadlineText = append(n.HeadlineText, uint16(0))
+			n.HeadlineText = append(n.HeadlineText, uint16(0))
+		}
+	}
+
+	n.Header.NumberOfHeadlines = uint32(len(selected))
+}
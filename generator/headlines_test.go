@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSelectHeadlines(t *testing.T) {
+	at := func(minutes int) time.Time {
+		return goldenTime.Add(time.Duration(minutes) * time.Minute)
+	}
+
+	articles := []news.Article{
+		{Title: "national old", Topic: news.NationalNews, Published: at(-90)},
+		{Title: "demoted", Topic: news.NationalNews, Published: at(0), Demoted: true},
+		{Title: "national new", Topic: news.NationalNews, Published: at(-10)},
+		{Title: "national undated", Topic: news.NationalNews},
+		{Title: "sports", Topic: news.Sports, Published: at(-30)},
+		{Title: "business new", Topic: news.Business, Published: at(-5)},
+		{Title: "business old", Topic: news.Business, Published: at(-60)},
+		{Title: "pinned", Topic: news.Business, Pinned: true},
+		{Title: "international", Topic: news.InternationalNews, Published: at(-120)},
+	}
+
+	titles := func(indexes []int) []string {
+		var titles []string
+		for _, i := range indexes {
+			titles = append(titles, articles[i].Title)
+		}
+		return titles
+	}
+
+	want := []string{"pinned", "national new", "international", "sports", "business new", "national old", "business old", "national undated", "demoted"}
+	if got := titles(selectHeadlines(articles, MaxHeadlines)); !reflect.DeepEqual(got, want) {
+		t.Errorf("headlines = %q, want %q", got, want)
+	}
+
+	if got := titles(selectHeadlines(articles, 3)); !reflect.DeepEqual(got, want[:3]) {
+		t.Errorf("3 headlines = %q, want %q", got, want[:3])
+	}
+}
+
+func TestGenerateMenuHeadline(t *testing.T) {
+	articles := []news.Article{
+		{Title: "Hurricane Warning for San Juan, Carolina, Fajardo and 2 more", MenuHeadline: "Hurricane Warning", Topic: news.NationalNews},
+		{Title: "Cangrejeros ganan el primer juego de la final", Topic: news.Sports},
+	}
+
+	result, err := Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Articles: articles, Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Hurricane Warning", "Cangrejeros ganan el primer juego de la final"}
+	if !reflect.DeepEqual(parsed.Headlines, want) || parsed.Articles[0].Title != articles[0].Title {
+		t.Errorf("headlines = %q, want %q with the full title in the channel", parsed.Headlines, want)
+	}
+}
+
+func TestGeneratePinnedLeadsEveryHour(t *testing.T) {
+	text := func(s string) *string { return &s }
+	alert := news.Article{Title: "Hurricane Warning for San Juan", MenuHeadline: "Hurricane Warning", Content: text("A hurricane warning is in effect for San Juan."), Topic: news.NationalNews, Published: goldenTime.Add(-5 * time.Hour), Pinned: true}
+	feed := news.Article{Title: "Vuelven las lluvias", Content: text("Las lluvias regresan al área metropolitana."), Topic: news.NationalNews, Published: goldenTime.Add(-time.Minute)}
+
+	cacheDir := t.TempDir()
+	var firstID uint32
+	for hour := 0; hour < 3; hour++ {
+		result, err := Generate(context.Background(), Options{
+			CacheDir: cacheDir,
+			Signer:   testSigner(t),
+			Source:   &newstest.Source{Articles: []news.Article{feed, alert}, Logo: testLogo},
+			Clock:    FixedClock(goldenTime.Add(time.Duration(hour) * time.Hour)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := DecodeFile(result.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The feed article was published in the first hour, but the alert is written again.
+		if len(parsed.Headlines) == 0 || parsed.Headlines[0] != "Hurricane Warning" {
+			t.Fatalf("hour %d headlines = %q, want the alert first", hour, parsed.Headlines)
+		}
+		if hour == 0 {
+			firstID = parsed.Articles[0].ID
+		} else if len(parsed.Articles) != 1 || parsed.Articles[0].ID != firstID {
+			t.Errorf("hour %d articles = %+v, want only the alert with ID %d", hour, parsed.Articles, firstID)
+		}
+	}
+}
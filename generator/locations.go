@@ -0,0 +1,92 @@
+package generator
+
+import "WiiNewsPR/news"
+
+type Location struct {
+	TextOffset   uint32
+	Latitude     int16
+	Longitude    int16
+	CountryCode  uint8
+	RegionCode   uint8
+	LocationCode uint16
+	Zoom         uint8
+	_            [3]byte
+}
+
+func CoordinateEncode(value float64) int16 {
+	value /= 0.0054931640625
+	return int16(value)
+}
+
+// sanJuan is where articles without a location of their own are placed.
+var sanJuan = news.Location{Name: news.SanJuanName, Latitude: news.SanJuanLatitude, Longitude: news.SanJuanLongitude}
+
+// placeKey identifies a place as the file stores it.
+type placeKey struct {
+	name                string
+	latitude, longitude int16
+}
+
+// placeArticles lists the distinct locations of the articles in n.places, in the order they first
+// appear, and returns the index of each article's location among them. Articles without a location
+// are placed in San Juan, which is also the only place of a file without articles. The table only
+// has room for one location per article, so an article covering several places is shown at its
+// main one.
+func (n *News) placeArticles() []uint32 {
+	n.places = nil
+	seen := map[placeKey]uint32{}
+	indexes := make([]uint32, len(n.articles))
+
+	for i, article := range n.articles {
+		location := sanJuan
+		if article.Location != nil {
+			location = *article.Location
+		}
+		if location.Name == "" {
+			location.Name = sanJuan.Name
+		}
+
+		key := placeKey{location.Name, CoordinateEncode(location.Latitude), CoordinateEncode(location.Longitude)}
+		index, ok := seen[key]
+		if !ok {
+			index = uint32(len(n.places))
+			seen[key] = index
+			n.places = append(n.places, location)
+		}
+		indexes[i] = index
+	}
+
+	if len(n.places) == 0 {
+		n.places = []news.Location{sanJuan}
+	}
+	return indexes
+}
+
+func (n *News) MakeLocationTable() {
+	n.Header.LocationTableOffset = n.GetCurrentSize()
+
+	for _, place := range n.places {
+		n.Locations = append(n.Locations, Location{
+			TextOffset:   0,
+			Latitude:     CoordinateEncode(place.Latitude),
+			Longitude:    CoordinateEncode(place.Longitude),
+			CountryCode:  0,
+			RegionCode:   0,
+			LocationCode: 0,
+			Zoom:         6,
+		})
+	}
+
+	// Set text offset and add location name
+	for i, place := range n.places {
+		n.Locations[i].TextOffset = n.GetCurrentSize()
+		encoded := encodeText(place.Name)
+		n.LocationText = append(n.LocationText, encoded...)
+		n.LocationText = append(n.LocationText, 0)
+		for n.GetCurrentSize()%4 != 0 {
+			n.LocationText = append(n.LocationText, 0)
+		}
+	}
+
+	n.Header.NumberOfLocations = uint32(len(n.Locations))
+}
@@ -0,0 +1,78 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGenerateLocations(t *testing.T) {
+	ponce := news.Location{Name: "Ponce", Latitude: 18.011, Longitude: -66.614}
+	juanaDiaz := news.Location{Name: "Juana Díaz", Latitude: 18.053, Longitude: -66.507}
+	mayaguez := news.Location{Name: "Mayagüez", Latitude: 18.201, Longitude: -67.139}
+
+	for _, test := range []struct {
+		name     string
+		articles []news.Article
+		places   []string
+		indexes  []uint32
+	}{
+		{
+			name:   "empty",
+			places: []string{news.SanJuanName},
+		},
+		{
+			name: "places",
+			articles: []news.Article{
+				{Title: "Flood Warning for Ponce and Juana Díaz", Topic: news.NationalNews, Location: &ponce, Locations: []news.Location{ponce, juanaDiaz}},
+				{Title: "Vuelven las lluvias al área metro", Topic: news.NationalNews},
+				{Title: "Tapones en el expreso Las Américas", Topic: news.NationalNews, Location: &sanJuan},
+				{Title: "Centros de acopio abiertos este sábado", Topic: news.NationalNews, Location: &mayaguez},
+				{Title: "Leones de Ponce ganan el primer juego", Topic: news.Sports, Location: &ponce},
+			},
+			places:  []string{"Ponce", news.SanJuanName, "Mayagüez"},
+			indexes: []uint32{0, 1, 1, 2, 0},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Generate(context.Background(), Options{
+				CacheDir: t.TempDir(),
+				Signer:   testSigner(t),
+				Source:   &newstest.Source{Articles: test.articles, Logo: testLogo},
+				Clock:    FixedClock(goldenTime),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := DecodeFile(result.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var places []string
+			for _, location := range parsed.Locations {
+				places = append(places, location.Name)
+			}
+			if !reflect.DeepEqual(places, test.places) {
+				t.Errorf("places = %q, want %q", places, test.places)
+			}
+
+			var indexes []uint32
+			for _, article := range parsed.Articles {
+				indexes = append(indexes, article.LocationIndex)
+			}
+			if !reflect.DeepEqual(indexes, test.indexes) {
+				t.Errorf("location indexes = %v, want %v", indexes, test.indexes)
+			}
+
+			if len(test.articles) > 0 {
+				if first := parsed.Locations[0]; first.Latitude != CoordinateEncode(ponce.Latitude) || first.Longitude != CoordinateEncode(ponce.Longitude) || first.Zoom != 6 {
+					t.Errorf("Ponce = %+v", first.Location)
+				}
+			}
+		})
+	}
+}
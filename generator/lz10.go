@@ -0,0 +1,54 @@
+package generator
+
+import "fmt"
+
+// lz10Magic starts every LZ10 stream, followed by the 24-bit little-endian decompressed size.
+const lz10Magic = 0x10
+
+// decompressLZ10 reverses lz10.Compress. lz10.Decompress overwrites the first flag byte of its
+// input and panics on malformed streams, so files are read back with this instead.
+func decompressLZ10(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != lz10Magic {
+		return nil, fmt.Errorf("%w: not LZ10 compressed", ErrMalformedFile)
+	}
+
+	size := int(data[1]) | int(data[2])<<8 | int(data[3])<<16
+	out := make([]byte, 0, size)
+	in := data[4:]
+
+	for len(out) < size {
+		if len(in) == 0 {
+			return nil, fmt.Errorf("%w: compressed data ends early", ErrMalformedFile)
+		}
+
+		flags := in[0]
+		in = in[1:]
+
+		for bit := 0; bit < 8 && len(out) < size; bit++ {
+			if flags&(0x80>>bit) == 0 {
+				if len(in) < 1 {
+					return nil, fmt.Errorf("%w: compressed data ends early", ErrMalformedFile)
+				}
+				out = append(out, in[0])
+				in = in[1:]
+				continue
+			}
+
+			if len(in) < 2 {
+				return nil, fmt.Errorf("%w: compressed data ends early", ErrMalformedFile)
+			}
+			length := int(in[0]>>4) + 3
+			distance := (int(in[0]&0x0F)<<8 | int(in[1])) + 1
+			in = in[2:]
+
+			if distance > len(out) {
+				return nil, fmt.Errorf("%w: back-reference before the start of the data", ErrMalformedFile)
+			}
+			for i := 0; i < length; i++ {
+				out = append(out, out[len(out)-distance])
+			}
+		}
+	}
+
+	return out[:size], nil
+}
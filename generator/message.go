@@ -0,0 +1,47 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"errors"
+	"log"
+)
+
+// GetMessage fetches the urgent message, keeping it only while it is active. The message is
+// optional, so a file or feed that can't be read leaves it out rather than the whole hour. Only a
+// message the source itself rejects as invalid is an error.
+func (n *News) GetMessage(source news.MessageSource) error {
+	if source == nil {
+		return nil
+	}
+
+	message, err := source.GetMessage()
+	if errors.Is(err, news.ErrInvalidMessage) {
+		return err
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to fetch the urgent message: %v\n", err)
+		return nil
+	}
+
+	if message.Active(n.currentTime) {
+		n.message = message
+	}
+	return nil
+}
+
+// MakeMessage writes the urgent message, if there is one, and points the header at it.
+func (n *News) MakeMessage() {
+	if n.message == nil {
+		return
+	}
+
+	n.Header.MessageOffset = n.GetCurrentSize()
+	n.MessageText = append(n.MessageText, encodeText(n.message.Text)...)
+
+	// Null terminator
+	n.MessageText = append(n.MessageText, 0)
+
+	for n.GetCurrentSize()%4 != 0 {
+		n.MessageText = append(n.MessageText, uint16(0))
+	}
+}
@@ -0,0 +1,128 @@
+package generator
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/message"
+	"WiiNewsPR/news/newstest"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// failingMessage fails every fetch with err.
+type failingMessage struct {
+	err error
+}
+
+func (s failingMessage) GetMessage() (*news.Message, error) {
+	return nil, s.err
+}
+
+func TestGenerateMessage(t *testing.T) {
+	text := "Aviso de huracán en efecto para todo Puerto Rico"
+
+	tests := []struct {
+		name    string
+		source  news.MessageSource
+		message string
+	}{
+		{"none", nil, ""},
+		{"active", message.NewStatic(text, goldenTime.Add(time.Hour)), text},
+		{"expired", message.NewStatic(text, goldenTime), ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := Generate(context.Background(), Options{
+				CacheDir: t.TempDir(),
+				Signer:   testSigner(t),
+				Source:   &newstest.Source{Logo: testLogo},
+				Clock:    FixedClock(goldenTime),
+				Message:  test.source,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parsed, err := DecodeFile(result.Data)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if parsed.Message != test.message || (parsed.Header.MessageOffset != 0) != (test.message != "") {
+				t.Errorf("message = %q at offset %d, want %q", parsed.Message, parsed.Header.MessageOffset, test.message)
+			}
+		})
+	}
+
+	// A feed that is down only leaves the message out.
+	result, err := Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Message:  failingMessage{errors.New("feed unavailable")},
+	})
+	if err != nil || result.Message != nil {
+		t.Errorf("unavailable feed: message = %v, err = %v", result.Message, err)
+	}
+
+	_, err = Generate(context.Background(), Options{
+		CacheDir: t.TempDir(),
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Message:  failingMessage{fmt.Errorf("%w: no expiry", news.ErrInvalidMessage)},
+	})
+
+	var genErr *Error
+	if !errors.As(err, &genErr) || genErr.Stage != StageMessage {
+		t.Errorf("err = %v, want a message stage error", err)
+	}
+}
+
+func TestReplayMessage(t *testing.T) {
+	cacheDir := t.TempDir()
+	expires := goldenTime.Add(2 * time.Hour)
+
+	original, err := Generate(context.Background(), Options{
+		CacheDir: cacheDir,
+		Signer:   testSigner(t),
+		Source:   &newstest.Source{Logo: testLogo},
+		Clock:    FixedClock(goldenTime),
+		Message:  message.NewStatic("Toque de queda desde las 7:00 p.m.", expires),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay := func(now time.Time) *ParsedFile {
+		t.Helper()
+
+		result, err := Replay(context.Background(), Options{
+			CacheDir: cacheDir,
+			Signer:   testSigner(t),
+			Source:   &newstest.Source{Logo: testLogo},
+			Clock:    FixedClock(now),
+		}, goldenTime, goldenTime.Hour())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := DecodeFile(result.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	if parsed := replay(goldenTime.Add(time.Hour)); parsed.Message != original.Message.Text {
+		t.Errorf("message before expiry = %q, want %q", parsed.Message, original.Message.Text)
+	}
+
+	if parsed := replay(expires); parsed.Message != "" || parsed.Header.MessageOffset != 0 {
+		t.Errorf("message after expiry = %q, want none", parsed.Message)
+	}
+}
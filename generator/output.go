@@ -0,0 +1,12 @@
+package generator
+
+import "context"
+
+// OutputSink receives generated files. Implementations for a directory, object storage and
+// WebDAV are in the sink package.
+type OutputSink interface {
+	// Write stores data under key, a slash-separated path such as v2/1/049/news.bin.18, and
+	// returns where it ended up. The replacement must be atomic: a reader gets either the
+	// previous file or the new one, never part of it.
+	Write(ctx context.Context, key string, data []byte) (string, error)
+}
@@ -0,0 +1,252 @@
+package generator
+
+import (
+	"WiiNewsPR/signing"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// ErrMalformedFile is returned when a news file can't be parsed back.
+var ErrMalformedFile = errors.New("malformed news file")
+
+// ParsedFile is a news file read back from its binary form. It is used to inspect generated files.
+type ParsedFile struct {
+	Header    Header
+	Headlines []string
+	// Message is the urgent message, empty if there is none.
+	Message    string
+	Articles   []ParsedArticle
+	Topics     []ParsedTopic
+	Locations  []ParsedLocation
+	Images     []Image
+	Timestamps []Timestamp
+}
+
+// ParsedArticle is an entry of the article table with its text decoded.
+type ParsedArticle struct {
+	Article
+	Title string
+	Body  string
+	// Topic is the name of the topic listing the article for this hour, if any.
+	Topic string
+}
+
+// ParsedTopic is an entry of the topic table with its name and timestamps decoded.
+type ParsedTopic struct {
+	Topic
+	Name       string
+	Timestamps []Timestamp
+}
+
+// ParsedLocation is an entry of the location table with its name decoded.
+type ParsedLocation struct {
+	Location
+	Name string
+}
+
+// DecodeFile parses a signed and compressed news file as served to the Wii.
+func DecodeFile(data []byte) (*ParsedFile, error) {
+	if len(data) < 64+signing.SignatureSize {
+		return nil, fmt.Errorf("%w: file is too small to contain a signature", ErrMalformedFile)
+	}
+
+	payload, err := decompressLZ10(data[64+signing.SignatureSize:])
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseFile(payload)
+}
+
+// ParseFile parses a decompressed news file, checking that every table and string lies inside it.
+func ParseFile(payload []byte) (*ParsedFile, error) {
+	p := &ParsedFile{}
+	if err := binary.Read(bytes.NewReader(payload), binary.BigEndian, &p.Header); err != nil {
+		return nil, fmt.Errorf("%w: failed to read header: %v", ErrMalformedFile, err)
+	}
+
+	h := p.Header
+	if h.Filesize != uint32(len(payload)) {
+		return nil, fmt.Errorf("%w: header says %d bytes, file has %d", ErrMalformedFile, h.Filesize, len(payload))
+	}
+
+	headlines := make([]Headlines, h.NumberOfHeadlines)
+	if err := readTable(payload, h.HeadlinesTableOffset, headlines); err != nil {
+		return nil, fmt.Errorf("headlines table: %w", err)
+	}
+
+	for _, headline := range headlines {
+		text, err := readText(payload, headline.HeadlineOffset, headline.HeadlineSize)
+		if err != nil {
+			return nil, fmt.Errorf("headline: %w", err)
+		}
+		p.Headlines = append(p.Headlines, text)
+	}
+
+	if h.MessageOffset != 0 {
+		message, err := readTerminatedText(payload, h.MessageOffset)
+		if err != nil {
+			return nil, fmt.Errorf("message: %w", err)
+		}
+		p.Message = message
+	}
+
+	topicTable := make([]Topic, h.NumberOfTopics)
+	if err := readTable(payload, h.TopicTableOffset, topicTable); err != nil {
+		return nil, fmt.Errorf("topic table: %w", err)
+	}
+
+	for i, topic := range topicTable {
+		parsed := ParsedTopic{Topic: topic}
+
+		// The first topic is a placeholder without text or timestamps.
+		if i > 0 {
+			name, err := readTerminatedText(payload, topic.TextOffset)
+			if err != nil {
+				return nil, fmt.Errorf("topic %d name: %w", i, err)
+			}
+			parsed.Name = name
+
+			parsed.Timestamps = make([]Timestamp, topic.NumberOfArticles)
+			if err = readTable(payload, topic.TimestampTableOffset, parsed.Timestamps); err != nil {
+				return nil, fmt.Errorf("topic %d timestamps: %w", i, err)
+			}
+			p.Timestamps = append(p.Timestamps, parsed.Timestamps...)
+		}
+
+		p.Topics = append(p.Topics, parsed)
+	}
+
+	locations := make([]Location, h.NumberOfLocations)
+	if err := readTable(payload, h.LocationTableOffset, locations); err != nil {
+		return nil, fmt.Errorf("location table: %w", err)
+	}
+
+	for i, location := range locations {
+		name, err := readTerminatedText(payload, location.TextOffset)
+		if err != nil {
+			return nil, fmt.Errorf("location %d name: %w", i, err)
+		}
+		p.Locations = append(p.Locations, ParsedLocation{Location: location, Name: name})
+	}
+
+	articles := make([]Article, h.NumberOfArticles)
+	if err := readTable(payload, h.ArticleTableOffset, articles); err != nil {
+		return nil, fmt.Errorf("article table: %w", err)
+	}
+
+	p.Images = make([]Image, h.NumberOfImages)
+	if err := readTable(payload, h.ImagesTableOffset, p.Images); err != nil {
+		return nil, fmt.Errorf("image table: %w", err)
+	}
+
+	for i, article := range articles {
+		title, err := readText(payload, article.HeadlineOffset, article.HeadlineSize)
+		if err != nil {
+			return nil, fmt.Errorf("article %d title: %w", i, err)
+		}
+
+		body, err := readText(payload, article.ArticleTextOffset, article.ArticleTextSize)
+		if err != nil {
+			return nil, fmt.Errorf("article %d body: %w", i, err)
+		}
+
+		if article.PictureIndex != NoPicture && article.PictureIndex >= h.NumberOfImages {
+			return nil, fmt.Errorf("%w: article %d points to missing image %d", ErrMalformedFile, i, article.PictureIndex)
+		}
+
+		if article.LocationIndex >= h.NumberOfLocations {
+			return nil, fmt.Errorf("%w: article %d points to missing location %d", ErrMalformedFile, i, article.LocationIndex)
+		}
+
+		parsed := ParsedArticle{
+			Article: article,
+			Title:   title,
+			Body:    body,
+		}
+
+		for _, topic := range p.Topics {
+			for _, timestamp := range topic.Timestamps {
+				if timestamp.ArticleNumber == article.ID && timestamp.Time == article.UpdatedTime {
+					parsed.Topic = topic.Name
+				}
+			}
+		}
+
+		p.Articles = append(p.Articles, parsed)
+	}
+
+	for i, image := range p.Images {
+		if err := checkRange(payload, image.PictureOffset, image.PictureSize); err != nil {
+			return nil, fmt.Errorf("image %d: %w", i, err)
+		}
+
+		if image.CaptionSize > 0 {
+			if _, err := readText(payload, image.CaptionOffset, image.CaptionSize); err != nil {
+				return nil, fmt.Errorf("image %d caption: %w", i, err)
+			}
+		}
+	}
+
+	return p, nil
+}
+
+func checkRange(payload []byte, offset, size uint32) error {
+	if uint64(offset)+uint64(size) > uint64(len(payload)) {
+		return fmt.Errorf("%w: %d bytes at offset %d exceed the file size of %d", ErrMalformedFile, size, offset, len(payload))
+	}
+
+	return nil
+}
+
+// readTable decodes len(table) fixed size entries starting at offset.
+func readTable[T any](payload []byte, offset uint32, table []T) error {
+	if len(table) == 0 {
+		return nil
+	}
+
+	if err := checkRange(payload, offset, uint32(binary.Size(table))); err != nil {
+		return err
+	}
+
+	return binary.Read(bytes.NewReader(payload[offset:]), binary.BigEndian, table)
+}
+
+// readText decodes a UTF-16 string of size bytes.
+func readText(payload []byte, offset, size uint32) (string, error) {
+	if size%2 != 0 {
+		return "", fmt.Errorf("%w: odd text size %d", ErrMalformedFile, size)
+	}
+
+	if err := checkRange(payload, offset, size); err != nil {
+		return "", err
+	}
+
+	encoded := make([]uint16, size/2)
+	for i := range encoded {
+		encoded[i] = binary.BigEndian.Uint16(payload[offset+uint32(i)*2:])
+	}
+
+	return string(utf16.Decode(encoded)), nil
+}
+
+// readTerminatedText decodes a null terminated UTF-16 string.
+func readTerminatedText(payload []byte, offset uint32) (string, error) {
+	var encoded []uint16
+	for i := offset; ; i += 2 {
+		if err := checkRange(payload, i, 2); err != nil {
+			return "", err
+		}
+
+		char := binary.BigEndian.Uint16(payload[i:])
+		if char == 0 {
+			break
+		}
+		encoded = append(encoded, char)
+	}
+
+	return string(utf16.Decode(encoded)), nil
+}
@@ -0,0 +1,105 @@
+package generator
+
+import (
+	"WiiNewsPR/cache"
+	"WiiNewsPR/news/endi"
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Replay builds the file for hour again from the articles cached in the slot generated in the hour
+// of at, and writes it to Options.Output. The file gets the timestamps of the run that wrote the
+// slot, so it expires when that run's file would have. The slot's message is kept while it is
+// still active by the clock. Nothing is fetched and the cache is left as it is. It fails with
+// ErrNotCached when there is no such slot, or it was written before whole articles were cached.
+// Like Generate, it holds the cache lock while it runs.
+func Replay(ctx context.Context, opts Options, at time.Time, hour int) (Result, error) {
+	if opts.Signer == nil {
+		return Result{}, &Error{Stage: StageSign, Err: ErrNoSigner}
+	}
+
+	opts.setDefaults()
+
+	// A generation running at the same time prunes the slots and their pictures, so hold the same
+	// lock it does while the slot is read.
+	unlock, err := cache.Acquire(ctx, opts.CacheStore, opts.LockTimeout)
+	if err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	result, err := replay(ctx, opts, at, hour)
+	if unlockErr := unlock(); unlockErr != nil {
+		log.Printf("Warning: Failed to release the cache lock: %v\n", unlockErr)
+	}
+
+	return result, err
+}
+
+func replay(ctx context.Context, opts Options, at time.Time, hour int) (Result, error) {
+	n := News{}
+	n.retention = opts.Retention
+	n.currentCountryCode = opts.CountryCode
+	n.currentLanguageCode = opts.LanguageCode
+	n.hours = []int{hour}
+	// The source only provides the logo here.
+	n.newsSource = opts.Source
+	if n.newsSource == nil {
+		n.newsSource = endi.NewEndi()
+	}
+
+	var err error
+	n.cacheStore = opts.CacheStore
+	if n.cache, err = n.cacheStore.Load(ctx); err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	slot, ok := n.cache.Slot(at)
+	if !ok {
+		return Result{}, &Error{Stage: StageCache, Err: fmt.Errorf("%w: no slot for %s", ErrNotCached, at.UTC().Format(time.RFC3339))}
+	}
+
+	n.currentTime = slot.GeneratedAt.UTC()
+	n.readPastEntries()
+
+	// A message that has expired since must not come back with the rebuilt file.
+	if slot.Message.Active(opts.Clock.Now()) {
+		n.message = slot.Message
+	}
+
+	// Every article keeps the ID and publication time it was written with. For updates that is
+	// the story they replace, so its earlier version leaves the timestamp table as it did then.
+	for _, entry := range slot.Articles {
+		if entry.Article == nil {
+			return Result{}, &Error{Stage: StageCache, Err: fmt.Errorf("%w: slot for %s only has titles", ErrNotCached, slot.GeneratedAt.Format(time.RFC3339))}
+		}
+	}
+
+	if n.articles, err = cache.LoadImages(ctx, n.cacheStore, slot); err != nil {
+		return Result{}, &Error{Stage: StageCache, Err: err}
+	}
+
+	n.updates = map[int]cache.Entry{}
+	for i, entry := range slot.Articles {
+		n.updates[i] = entry
+		for _, past := range n.pastEntries {
+			if past.SameStory(entry) {
+				n.updates[i] = past
+			}
+		}
+	}
+	n.dropSupersededTimestamps()
+
+	payload, err := n.MakeFile()
+	if err != nil {
+		return Result{}, &Error{Stage: StageEncode, Err: err}
+	}
+
+	result, err := n.pack(ctx, opts.Signer, payload)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return output(ctx, opts.Output, result)
+}
@@ -1,13 +1,11 @@
-package main
+package generator
 
 import (
-	"WiiNewsPR/news/endi"
 	_ "embed"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"os"
-	"time"
 )
 
 type Source struct {
@@ -22,16 +20,16 @@ type Source struct {
 	CopyrightOffset uint32
 }
 
-func (n *News) GetNewsArticles() {
-	n.newsSource = endi.NewEndi(n.oldArticleTitles)
+func (n *News) GetNewsArticles() error {
 	var err error
 	n.articles, err = n.newsSource.GetArticles()
 	if err != nil {
-		panic(err)
+		return err
 	}
 
 	// Save articles to file for inspection (Debug)
 	// n.debugSaveArticles()
+	return nil
 }
 
 func (n *News) MakeSourceTable() {
@@ -136,7 +134,7 @@ func (n *News) debugSaveArticles() {
 	}
 
 	// Create filename with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
+	timestamp := n.currentTime.Format("2006-01-02_15-04-05")
 	filename := fmt.Sprintf("debug/articles_%s.json", timestamp)
 
 	// Save to JSON file
@@ -0,0 +1,74 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// Embed the zone database, so zones resolve on hosts without one, such as Lambda.
+	_ "time/tzdata"
+)
+
+// ParseTimeZones reads a comma-separated list of IANA zone names, such as
+// "America/Puerto_Rico,America/Chicago". An empty list returns nil.
+func ParseTimeZones(list string) ([]*time.Location, error) {
+	var zones []*time.Location
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		zone, err := time.LoadLocation(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", name, err)
+		}
+		zones = append(zones, zone)
+	}
+
+	return zones, nil
+}
+
+// LocalNow returns the time of the clock in the first of the time zones, whose hour is
+// Result.Hour.
+func (o Options) LocalNow() time.Time {
+	clock := o.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+
+	now := clock.Now()
+	if len(o.TimeZones) > 0 {
+		now = now.In(o.TimeZones[0])
+	}
+	return now
+}
+
+// CurrentHours returns the hours of the files the clock's time is for, one per distinct local hour
+// of the time zones.
+func (o Options) CurrentHours() []int {
+	clock := o.Clock
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return localHours(clock.Now(), o.TimeZones)
+}
+
+// localHours returns the hour of t in each zone, without repeats. Without zones it is the hour
+// in t's own zone.
+func localHours(t time.Time, zones []*time.Location) []int {
+	if len(zones) == 0 {
+		return []int{t.Hour()}
+	}
+
+	var hours []int
+	seen := map[int]bool{}
+	for _, zone := range zones {
+		hour := t.In(zone).Hour()
+		if !seen[hour] {
+			seen[hour] = true
+			hours = append(hours, hour)
+		}
+	}
+	return hours
+}
@@ -0,0 +1,126 @@
+package generator
+
+import (
+	"WiiNewsPR/news/newstest"
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGenerateTimeZones(t *testing.T) {
+	puertoRico := time.FixedZone("AST", -4*60*60)
+	chicago := time.FixedZone("CDT", -5*60*60)
+	eastern := time.FixedZone("EDT", -4*60*60)
+	india := time.FixedZone("IST", 5*60*60+30*60)
+
+	tests := []struct {
+		name  string
+		zones []*time.Location
+		hours []int
+	}{
+		{"clock zone", nil, []int{18}},
+		{"one zone", []*time.Location{puertoRico}, []int{14}},
+		{"several zones", []*time.Location{puertoRico, chicago}, []int{14, 13}},
+		{"same hour", []*time.Location{puertoRico, eastern, chicago}, []int{14, 13}},
+		{"half hour offset", []*time.Location{india}, []int{0}},
+	}
+
+	var data []byte
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			output := &memorySink{files: map[string][]byte{}}
+			result, err := Generate(context.Background(), Options{
+				CacheDir:  t.TempDir(),
+				Signer:    testSigner(t),
+				Source:    &newstest.Source{Logo: testLogo},
+				Clock:     FixedClock(goldenTime),
+				TimeZones: test.zones,
+				Output:    output,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(result.Hours, test.hours) || result.Hour != test.hours[0] {
+				t.Errorf("hours = %v (hour %d), want %v", result.Hours, result.Hour, test.hours)
+			}
+
+			if len(output.files) != len(test.hours) || len(result.Locations) != len(test.hours) {
+				t.Fatalf("wrote %d files to %v, want %d", len(output.files), result.Locations, len(test.hours))
+			}
+			for _, path := range result.Paths() {
+				if !bytes.Equal(output.files[path], result.Data) {
+					t.Errorf("%s differs from the generated file", path)
+				}
+			}
+
+			// The zones only pick the file names; the timestamps inside are the same.
+			if data == nil {
+				data = result.Data
+			} else if !bytes.Equal(result.Data, data) {
+				t.Error("file contents depend on the time zones")
+			}
+		})
+	}
+}
+
+func TestGenerateClockZone(t *testing.T) {
+	generate := func(at time.Time) Result {
+		result, err := Generate(context.Background(), Options{
+			CacheDir: t.TempDir(),
+			Signer:   testSigner(t),
+			Source:   &newstest.Source{Logo: testLogo},
+			Clock:    FixedClock(at),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	utc := generate(goldenTime)
+	local := generate(goldenTime.In(time.FixedZone("AST", -4*60*60)))
+	if !bytes.Equal(utc.Data, local.Data) {
+		t.Error("timestamps depend on the zone of the clock")
+	}
+
+	if utc.Hour != 18 || local.Hour != 14 {
+		t.Errorf("hours = %d and %d, want 18 and 14", utc.Hour, local.Hour)
+	}
+}
+
+func TestParseTimeZones(t *testing.T) {
+	zones, err := ParseTimeZones(" America/Puerto_Rico, America/Chicago,")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(zones) != 2 || zones[0].String() != "America/Puerto_Rico" || zones[1].String() != "America/Chicago" {
+		t.Errorf("zones = %v", zones)
+	}
+
+	if zones, err = ParseTimeZones(""); err != nil || zones != nil {
+		t.Errorf("empty list = %v, %v", zones, err)
+	}
+
+	if _, err = ParseTimeZones("America/Nowhere"); err == nil {
+		t.Error("unknown zone was accepted")
+	}
+}
+
+func TestOptionsLocalNow(t *testing.T) {
+	opts := Options{
+		Clock:     FixedClock(goldenTime),
+		TimeZones: []*time.Location{time.FixedZone("AST", -4*60*60), time.FixedZone("CDT", -5*60*60)},
+	}
+
+	if now := opts.LocalNow(); now.Hour() != 14 || !now.Equal(goldenTime) {
+		t.Errorf("LocalNow() = %s", now)
+	}
+
+	if hours := opts.CurrentHours(); !reflect.DeepEqual(hours, []int{14, 13}) {
+		t.Errorf("CurrentHours() = %v", hours)
+	}
+}
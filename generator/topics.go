@@ -0,0 +1,120 @@
+package generator
+
+import (
+	"WiiNewsPR/cache"
+	"context"
+	"maps"
+)
+
+// FORK UPDATE: Since we only support English, we can hardcode the topics and their text here
+var topics = []string{"National News", "International News", "Sports", "Entertainment", "Business", "Science", "Technology"}
+
+type Topic struct {
+	TextOffset           uint32
+	NumberOfArticles     uint32
+	TimestampTableOffset uint32
+}
+
+// Timestamp handles the time an article was obtained.
+type Timestamp struct {
+	Time          uint32
+	ArticleNumber uint32
+}
+
+// ReadNewsCache creates the topic table as well as the timestamp table for articles.
+// This is quite an annoying job as for some reason it needs to make the timestamp table for every single article, even ones
+// from past hours. Due to this we are required to cache what articles we used.
+func (n *News) ReadNewsCache(ctx context.Context, store cache.Store) error {
+	n.cacheStore = store
+	var err error
+	n.cache, err = n.cacheStore.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	n.readPastEntries()
+	return nil
+}
+
+// readPastEntries fills the topic and timestamp tables with the cached articles of previous hours.
+func (n *News) readPastEntries() {
+	topicsLength := len(topics) + 1
+
+	n.topics = make([]Topic, topicsLength)
+	n.timestamps = make([][]Timestamp, topicsLength)
+
+	// Only slots from the retention window count, so a generator that was down for a while
+	// doesn't resurrect articles from days ago. A story updated in a later hour only keeps its
+	// latest version.
+	for _, slot := range n.cache.Recent(n.currentTime, n.retention) {
+		for _, article := range slot.Articles {
+			n.pastEntries = supersede(n.pastEntries, article)
+		}
+	}
+
+	for _, article := range n.pastEntries {
+		n.topics[article.Topic+1].NumberOfArticles++
+		n.timestamps[article.Topic+1] = append(n.timestamps[article.Topic+1], Timestamp{
+			Time:          article.Timestamp,
+			ArticleNumber: article.ID,
+		})
+	}
+}
+
+// supersede adds entry to entries, replacing an earlier version of the same story.
+func supersede(entries []cache.Entry, entry cache.Entry) []cache.Entry {
+	for i, existing := range entries {
+		if existing.SameStory(entry) {
+			entries[i] = entry
+			return entries
+		}
+	}
+	return append(entries, entry)
+}
+
+func (n *News) MakeTopicTable() {
+	// Move the placeholder into the field being written.
+	n.Header.TopicTableOffset = n.GetCurrentSize()
+	n.Topics = n.topics
+
+	topicsLength := len(topics) + 1
+	n.Header.NumberOfTopics = uint32(topicsLength)
+
+	// Now we copy all our data into the struct
+	for i := 1; i < topicsLength; i++ {
+		n.Topics[i].TimestampTableOffset = n.GetCurrentSize()
+		n.Topics[i].NumberOfArticles = uint32(len(n.timestamps[i]))
+		n.Timestamps = append(n.Timestamps, n.timestamps[i]...)
+	}
+
+	for i, topic := range topics {
+		n.Topics[i+1].TextOffset = n.GetCurrentSize()
+		n.TopicText = append(n.TopicText, encodeText(topic)...)
+		n.TopicText = append(n.TopicText, uint16(0))
+	}
+}
+
+// WriteNewsCache writes the found articles for the current hour.
+func (n *News) WriteNewsCache(ctx context.Context) error {
+	// Order everything into the cache slot
+	slot := cache.Slot{Message: n.message}
+	for i := range n.articles {
+		slot.Articles = append(slot.Articles, n.cacheEntry(i))
+	}
+
+	stored := n.cache.Images()
+	n.cache.SetSlot(n.currentTime, slot)
+	n.cache.Prune(n.currentTime, n.retention)
+
+	previous := maps.Clone(stored)
+	if err := cache.StoreImages(ctx, n.cacheStore, n.cache, stored); err != nil {
+		return err
+	}
+
+	if err := n.cacheStore.Save(ctx, n.cache); err != nil {
+		return err
+	}
+
+	cache.RemoveUnusedImages(ctx, n.cacheStore, n.cache, previous)
+	return nil
+}
@@ -0,0 +1,25 @@
+package generator
+
+import (
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// fixTime adjusts the timestamp to coincide with the Wii's UTC timestamp.
+func fixTime(value time.Time) uint32 {
+	return uint32((value.Unix() - 946684800) / 60)
+}
+
+// ParseTimestamp converts a timestamp of a news file, in minutes since 2000, back to a time.
+func ParseTimestamp(value uint32) time.Time {
+	return time.Unix(946684800+int64(value)*60, 0).UTC()
+}
+
+// encodeText converts text to UTF-16. Invalid UTF-8 becomes U+FFFD and null characters are
+// dropped, as the Wii would treat them as the end of the string.
+func encodeText(text string) []uint16 {
+	text = strings.ToValidUTF8(text, "\uFFFD")
+	text = strings.ReplaceAll(text, "\x00", "")
+	return utf16.Encode([]rune(text))
+}
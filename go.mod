@@ -6,4 +6,5 @@ require (
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/wii-tools/lzx v0.0.0-20221114001118-aaec5e424e43
 	golang.org/x/image v0.8.0
+	gopkg.in/yaml.v3 v3.0.1
 )
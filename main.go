@@ -1,142 +1,296 @@
 package main
 
 import (
-	"WiiNewsPR/news"
-	"bytes"
-	"encoding/binary"
+	"WiiNewsPR/backfill"
+	"WiiNewsPR/daemon"
+	"WiiNewsPR/generator"
+	"WiiNewsPR/schedule"
+	"WiiNewsPR/server"
+	"WiiNewsPR/signing"
+	"WiiNewsPR/sink"
+	"context"
 	"flag"
 	"fmt"
-	"hash/crc32"
-	"io"
 	"log"
+	"net/http"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"syscall"
 	"time"
-
-	"github.com/wii-tools/lzx/lz10"
 )
 
-type News struct {
-	Header          Header
-	Headlines       []Headlines
-	HeadlineText    []uint16
-	Topics          []Topic
-	Timestamps      []Timestamp
-	TopicText       []uint16
-	Articles        []Article
-	ArticleText     []uint16
-	Sources         []Source
-	SourcePictures  []byte
-	SourceCopyright []byte
-	Locations       []Location
-	LocationText    []uint16
-	Images          []Image
-	ImagesData      []byte
-	CaptionData     []uint16
-
-	newsSource news.Source
-
-	currentLanguageCode uint8
-	currentCountryCode  uint8
-	currentHour         int
-
-	// Titles of articles from previous hours. Required for making sure we don't have duplicates.
-	oldArticleTitles []string
-
-	// Placeholder for the timestamps for a specific topic.
-	timestamps [][]Timestamp
-
-	articles []news.Article
-
-	// Placeholder for the topics.
-	topics []Topic
-}
-
-var currentTime = 0
-
 func main() {
-	outputDir := flag.String("o", ".", "Output directory for generated files (default: . [current directory])")
-	cacheDir := flag.String("c", "./cache", "Cache directory for articles generated previously (default: ./cache)")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			runVerify(os.Args[2:])
+			return
+		case "sign-server":
+			runSignServer(os.Args[2:])
+			return
+		case "keygen":
+			runKeygen(os.Args[2:])
+			return
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "daemon":
+			runDaemon(os.Args[2:])
+			return
+		case "backfill":
+			runBackfill(os.Args[2:])
+			return
+		}
+	}
+
+	output := flag.String("o", ".", "Output directory, s3://bucket/prefix or WebDAV URL for generated files (default: . [current directory])")
+	generate := addGenerateFlags(flag.CommandLine)
+	dedupReport := flag.Bool("dedup-report", false, "Log every article dropped as a duplicate, and what it was merged into")
+	at := flag.String("at", "", "Generate as of this RFC 3339 timestamp instead of now (e.g. 2024-09-01T14:30:00-04:00)")
 	flag.Parse()
 
-	n := News{}
-	n.currentCountryCode = 49 // USA
-	n.currentLanguageCode = 1 // English
-
-	t := time.Now()
-	currentTime = int(t.Unix())
-	n.currentHour = t.Hour()
-
-	buffer := new(bytes.Buffer)
-	n.ReadNewsCache(*cacheDir)
-	n.GetNewsArticles()
-	n.MakeHeader()
-	n.MakeWiiMenuHeadlines()
-	n.MakeArticleTable()
-	n.MakeTopicTable()
-	n.MakeSourceTable()
-	n.WriteNewsCache(*cacheDir)
-	n.MakeLocationTable()
-	n.WriteImages()
-	n.Header.Filesize = n.GetCurrentSize()
-	n.WriteAll(buffer)
-
-	crcTable := crc32.MakeTable(crc32.IEEE)
-	checksum := crc32.Checksum(buffer.Bytes()[12:], crcTable)
-	n.Header.CRC32 = checksum
-
-	buffer.Reset()
-	n.WriteAll(buffer)
-
-	compressed, err := lz10.Compress(buffer.Bytes())
-	checkError(err)
-
-	// If the folder exists we can just continue
-	outputPath := filepath.Join(*outputDir, fmt.Sprintf("v2/%d/%03d", n.currentLanguageCode, n.currentCountryCode))
-	err = os.MkdirAll(outputPath, os.ModePerm)
-	if !os.IsExist(err) {
-		checkError(err)
+	clock, err := newClock(*at)
+	checkError(err)
+
+	opts, err := generate.options(clock)
+	checkError(err)
+
+	opts.Output, err = sink.Open(*output)
+	checkError(err)
+
+	result, err := generator.Generate(context.Background(), opts)
+	checkError(err)
+
+	for _, change := range result.Filtered {
+		log.Printf("Filter: %s\n", change)
+	}
+
+	if *dedupReport {
+		for _, merge := range result.Merges {
+			log.Printf("Duplicate: %s\n", merge)
+		}
+	}
+
+	log.Printf("Successfully generated %s\n", result.Location)
+}
+
+// runVerify checks the signature of existing news files against a public key.
+func runVerify(args []string) {
+	flags := flag.NewFlagSet("verify", flag.ExitOnError)
+	publicKeyPath := flags.String("p", "Public.pem", "RSA public key (or private key) to verify against")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s verify [-p Public.pem] news.bin...\n", os.Args[0])
+		flags.PrintDefaults()
 	}
+	flags.Parse(args)
 
-	outputFile := filepath.Join(outputPath, fmt.Sprintf("news.bin.%02d", n.currentHour))
-	err = os.WriteFile(outputFile, SignFile(compressed), 0666)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	key, err := signing.LoadPublicKey(*publicKeyPath)
 	checkError(err)
 
-	log.Printf("Successfully generated news file for %d/%03d at hour %02d\n", n.currentLanguageCode, n.currentCountryCode, n.currentHour)
+	failed := false
+	for _, path := range flags.Args() {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			err = signing.VerifyFile(data, key)
+		}
+
+		if err != nil {
+			log.Printf("%s: FAILED (%v)\n", path, err)
+			failed = true
+			continue
+		}
+
+		log.Printf("%s: OK\n", path)
+	}
+
+	if failed {
+		os.Exit(1)
+	}
 }
 
-func checkError(err error) {
+// newSigner picks the remote signing service when a URL is configured and the local key otherwise.
+func newSigner(keyPath, signerURL string, unsigned bool) (signing.Signer, error) {
+	if unsigned {
+		return signing.Unsigned{}, nil
+	}
+
+	if signerURL != "" {
+		return signing.NewRemoteSigner(signerURL, os.Getenv("WIINEWSPR_SIGNER_TOKEN")), nil
+	}
+
+	return signing.NewPEMSigner(keyPath)
+}
+
+// newClock returns a fixed clock when a timestamp is given and the system clock otherwise.
+func newClock(at string) (generator.Clock, error) {
+	if at == "" {
+		return generator.SystemClock{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, at)
 	if err != nil {
-		log.Fatalf("News Channel file generator has encountered a fatal error! Reason: %v\n", err)
+		return nil, fmt.Errorf("invalid -at timestamp: %w", err)
 	}
+
+	return generator.FixedClock(t), nil
 }
 
-func Write(writer io.Writer, data any) {
-	err := binary.Write(writer, binary.BigEndian, data)
+// runSignServer runs a small signing service so the private key can be kept away from the generator.
+func runSignServer(args []string) {
+	flags := flag.NewFlagSet("sign-server", flag.ExitOnError)
+	keyPath := flags.String("k", signing.KeyPathFromEnv(), "RSA private key used to sign digests (default: $WIINEWSPR_KEY or Private.pem)")
+	address := flags.String("l", "127.0.0.1:8089", "Address to listen on")
+	flags.Parse(args)
+
+	signer, err := signing.NewPEMSigner(*keyPath)
+	checkError(err)
+
+	log.Printf("Signing service listening on %s\n", *address)
+	err = http.ListenAndServe(*address, signing.NewServer(signer, os.Getenv("WIINEWSPR_SIGNER_TOKEN")))
 	checkError(err)
 }
 
-func (n *News) WriteAll(writer io.Writer) {
-	Write(writer, n.Header)
-	Write(writer, n.Headlines)
-	Write(writer, n.HeadlineText)
-	Write(writer, n.Articles)
-	Write(writer, n.ArticleText)
-	Write(writer, n.Topics)
-	Write(writer, n.Timestamps)
-	Write(writer, n.TopicText)
-	Write(writer, n.Sources)
-	Write(writer, n.SourcePictures)
-	Write(writer, n.Locations)
-	Write(writer, n.LocationText)
-	Write(writer, n.Images)
-	Write(writer, n.ImagesData)
-	Write(writer, n.CaptionData)
+// runServe hosts the generated files over HTTP like the official endpoint, for Dolphin or a patched Wii.
+func runServe(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	outputDir := flags.String("o", ".", "Directory the generated files are kept in")
+	address := flags.String("l", ":8080", "Address to listen on")
+	onDemand := flags.Bool("on-demand", true, "Generate the current hour's file when the console asks for it and it is missing or stale")
+	interval := flags.Duration("every", 0, "Also generate on a schedule, at every multiple of this interval (e.g. 1h); 0 disables it")
+	generate := addGenerateFlags(flags)
+	flags.Parse(args)
+
+	opts, err := generate.options(generator.SystemClock{})
+	checkError(err)
+
+	s := server.New(*outputDir, opts, *onDemand)
+	if *interval > 0 {
+		go s.Schedule(context.Background(), *interval)
+	}
+
+	log.Printf("Serving news files from %s on %s\n", *outputDir, *address)
+	err = http.ListenAndServe(*address, s)
+	checkError(err)
+}
+
+// runDaemon generates files on a cron schedule until it receives SIGTERM or an interrupt.
+func runDaemon(args []string) {
+	flags := flag.NewFlagSet("daemon", flag.ExitOnError)
+	output := flags.String("o", ".", "Directory, s3://bucket/prefix or WebDAV URL the generated files are written to")
+	spec := flags.String("schedule", schedule.Hourly, "Cron expression (minute hour day-of-month month day-of-week) of when to generate, in the first -tz zone")
+	runNow := flags.Bool("now", false, "Also generate straight away on start")
+	minBackoff := flags.Duration("min-backoff", daemon.DefaultMinBackoff, "Wait after the first failed attempt of a run; it doubles after every further failure")
+	maxBackoff := flags.Duration("max-backoff", daemon.DefaultMaxBackoff, "Longest wait between attempts of a run")
+	grace := flags.Duration("grace", daemon.DefaultGracePeriod, "How long a run in progress may continue after SIGTERM")
+	generate := addGenerateFlags(flags)
+	flags.Parse(args)
+
+	s, err := schedule.Parse(*spec)
+	checkError(err)
+
+	opts, err := generate.options(generator.SystemClock{})
+	checkError(err)
+
+	opts.Output, err = sink.Open(*output)
+	checkError(err)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	d := &daemon.Daemon{
+		Schedule:    s,
+		Options:     opts,
+		MinBackoff:  *minBackoff,
+		MaxBackoff:  *maxBackoff,
+		GracePeriod: *grace,
+	}
+
+	log.Printf("Generating news files to %s on schedule %q\n", *output, *spec)
+	err = d.Run(ctx, *runNow)
+	checkError(err)
+}
+
+// runBackfill rebuilds the hourly files that are missing or about to expire.
+func runBackfill(args []string) {
+	flags := flag.NewFlagSet("backfill", flag.ExitOnError)
+	output := flags.String("o", ".", "Directory, s3://bucket/prefix or WebDAV URL holding the generated files")
+	margin := flags.Duration("margin", backfill.DefaultMargin, "Also rebuild files that expire within this long")
+	dryRun := flags.Bool("n", false, "Only list the files that would be rebuilt")
+	at := flags.String("at", "", "Check as of this RFC 3339 timestamp instead of now")
+	generate := addGenerateFlags(flags)
+	flags.Parse(args)
+
+	clock, err := newClock(*at)
+	checkError(err)
+
+	opts, err := generate.options(clock)
+	checkError(err)
+
+	out, err := sink.Open(*output)
+	checkError(err)
+
+	b := &backfill.Backfill{Options: opts, Sink: out, Margin: *margin}
+	if *dryRun {
+		stale, err := b.Check(context.Background())
+		checkError(err)
+
+		for hour := 0; hour < 24; hour++ {
+			if reason, ok := stale[hour]; ok {
+				log.Printf("Hour %02d: %s\n", hour, reason)
+			}
+		}
+		log.Printf("%d files to rebuild\n", len(stale))
+		return
+	}
+
+	fills, err := b.Run(context.Background())
+	for _, fill := range fills {
+		log.Printf("Rebuilt %s\n", fill)
+	}
+	checkError(err)
+
+	log.Printf("Rebuilt %d files\n", len(fills))
 }
 
-func (n *News) GetCurrentSize() uint32 {
-	buffer := bytes.NewBuffer(nil)
-	n.WriteAll(buffer)
+// runKeygen creates a new key pair and prints the public key to patch into the channel.
+func runKeygen(args []string) {
+	flags := flag.NewFlagSet("keygen", flag.ExitOnError)
+	privatePath := flags.String("k", signing.DefaultKeyPath, "Where to write the private key")
+	publicPath := flags.String("p", "Public.pem", "Where to write the public key")
+	force := flags.Bool("f", false, "Overwrite existing key files")
+	flags.Parse(args)
+
+	if !*force {
+		for _, path := range []string{*privatePath, *publicPath} {
+			if _, err := os.Stat(path); err == nil {
+				log.Fatalf("%s already exists, use -f to overwrite it\n", path)
+			}
+		}
+	}
+
+	key, err := signing.GenerateKey()
+	checkError(err)
 
-	return uint32(buffer.Len())
+	publicKey, err := signing.EncodePublicKey(&key.PublicKey)
+	checkError(err)
+
+	err = os.WriteFile(*privatePath, signing.EncodePrivateKey(key), 0600)
+	checkError(err)
+
+	err = os.WriteFile(*publicPath, publicKey, 0644)
+	checkError(err)
+
+	log.Printf("Wrote %s and %s\n", *privatePath, *publicPath)
+	fmt.Printf("Channel public key (modulus, exponent %d):\n%X\n", key.PublicKey.E, signing.ChannelPublicKey(&key.PublicKey))
+}
+
+func checkError(err error) {
+	if err != nil {
+		log.Fatalf("News Channel file generator has encountered a fatal error! Reason: %v\n", err)
+	}
 }
@@ -0,0 +1,45 @@
+package news
+
+import (
+	"errors"
+	"log"
+)
+
+// Combined merges the articles of several sources, in order, so the articles of the first source
+// come first. The logo is the first one a source has.
+type Combined []Source
+
+// Combine returns a source with the articles of every source, in order.
+func Combine(sources ...Source) Combined {
+	return sources
+}
+
+// GetArticles skips a source that fails, so one unreachable feed doesn't empty the channel. It
+// only fails when every source did.
+func (c Combined) GetArticles() ([]Article, error) {
+	var articles []Article
+	var errs []error
+	for _, source := range c {
+		found, err := source.GetArticles()
+		if err != nil {
+			log.Printf("Warning: Failed to fetch source: %v\n", err)
+			errs = append(errs, err)
+			continue
+		}
+		articles = append(articles, found...)
+	}
+
+	if len(c) > 0 && len(errs) == len(c) {
+		return nil, errors.Join(errs...)
+	}
+	return articles, nil
+}
+
+func (c Combined) GetLogo() []byte {
+	for _, source := range c {
+		if logo := source.GetLogo(); len(logo) > 0 {
+			return logo
+		}
+	}
+	return nil
+}
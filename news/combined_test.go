@@ -0,0 +1,44 @@
+package news
+
+import (
+	"errors"
+	"testing"
+)
+
+// fixedSource returns a fixed set of articles, or err.
+type fixedSource struct {
+	articles []Article
+	logo     []byte
+	err      error
+}
+
+func (s fixedSource) GetArticles() ([]Article, error) {
+	return s.articles, s.err
+}
+
+func (s fixedSource) GetLogo() []byte {
+	return s.logo
+}
+
+func TestCombined(t *testing.T) {
+	alerts := fixedSource{articles: []Article{{Title: "Hurricane Warning"}}}
+	feed := fixedSource{articles: []Article{{Title: "Vuelven las lluvias"}, {Title: "Cangrejeros ganan"}}, logo: []byte{0xFF, 0xD8}}
+	failing := fixedSource{err: errors.New("unreachable")}
+
+	articles, err := Combine(alerts, failing, feed).GetArticles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(articles) != 3 || articles[0].Title != "Hurricane Warning" || articles[2].Title != "Cangrejeros ganan" {
+		t.Errorf("articles = %+v, want the alerts first", articles)
+	}
+
+	if logo := Combine(alerts, feed).GetLogo(); len(logo) != 2 {
+		t.Errorf("logo = %v, want the feed's", logo)
+	}
+
+	if _, err = Combine(failing, failing).GetArticles(); err == nil {
+		t.Error("expected an error when every source fails")
+	}
+}
@@ -1,5 +1,11 @@
 package news
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // Source represents a News source.
 type Source interface {
 	GetArticles() ([]Article, error)
@@ -7,11 +13,30 @@ type Source interface {
 }
 
 type Article struct {
-	Title     string
-	Content   *string
-	Topic     Topic
-	Location  *Location
+	Title string
+	// MenuHeadline is a shorter title for the Wii Menu ticker. The title is used when it is empty.
+	MenuHeadline string `json:",omitempty"`
+	Content      *string
+	Topic        Topic
+	Location     *Location
+	// Locations lists every place the article concerns, such as the municipalities under a
+	// weather alert, when there is more than one. Location is the main one.
+	Locations []Location `json:",omitempty"`
 	Thumbnail *Thumbnail
+	// Pinned articles are placed before the others, in the channel and the Wii Menu.
+	Pinned bool `json:",omitempty"`
+	// Published is when the source published the article, if it says. The Wii Menu headlines
+	// favour recent articles.
+	Published time.Time
+	// Demoted articles come after the others, and only make the Wii Menu when there aren't
+	// enough others.
+	Demoted bool `json:",omitempty"`
+
+	// Feed names the feed the article came from, such as "deportes" or "nws". Author and
+	// Categories are as the feed gives them. They are only used to filter articles.
+	Feed       string   `json:",omitempty"`
+	Author     string   `json:",omitempty"`
+	Categories []string `json:",omitempty"`
 }
 
 type Thumbnail struct {
@@ -31,3 +56,25 @@ const (
 	Science
 	Technology
 )
+
+// topicNames are the short names of the topics, as used in configuration.
+var topicNames = []string{"national", "international", "sports", "entertainment", "business", "science", "technology"}
+
+func (t Topic) String() string {
+	if t < 0 || int(t) >= len(topicNames) {
+		return fmt.Sprintf("topic %d", int(t))
+	}
+	return topicNames[t]
+}
+
+// ParseTopic reads a topic by its short name, such as "sports", or its full name, such as
+// "National News". Case doesn't matter.
+func ParseTopic(name string) (Topic, error) {
+	name = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(name)), " news")
+	for i, topicName := range topicNames {
+		if name == topicName {
+			return Topic(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown topic %q, want one of %s", name, strings.Join(topicNames, ", "))
+}
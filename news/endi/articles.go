@@ -34,6 +34,9 @@ type Channel struct {
 type Item struct {
 	Title        string       `xml:"title"`
 	Description  string       `xml:"description"`
+	PubDate      string       `xml:"pubDate"`
+	Creator      string       `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories   []string     `xml:"category"`
 	MediaContent MediaContent `xml:"http://search.yahoo.com/mrss/ content"`
 }
 
@@ -67,38 +70,68 @@ func (e *Endi) makeRequest(client *http.Client, feedURL string) (*http.Response,
 
 func (e *Endi) GetArticles() ([]news.Article, error) {
 
-	baseURL := "https://www.elnuevodia.com/arc/outboundfeeds/rss/category/%s/?outputType=xml"
-
-	feeds := map[news.Topic]string{
-		news.NationalNews:  fmt.Sprintf(baseURL, "noticias/locales"),
-		news.Sports:        fmt.Sprintf(baseURL, "deportes"),
-		news.Entertainment: fmt.Sprintf(baseURL, "entretenimiento"),
-		news.Business:      fmt.Sprintf(baseURL, "negocios"),
-		news.Science:       fmt.Sprintf(baseURL, "ciencia-ambiente"),
-		news.Technology:    fmt.Sprintf(baseURL, "tecnologia"),
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	baseURL := e.baseURL + "/arc/outboundfeeds/rss/category/%s/?outputType=xml"
+
+	// A slice rather than a map so the feeds are always fetched in the same order.
+	feeds := []struct {
+		topic    news.Topic
+		category string
+	}{
+		{news.NationalNews, "noticias/locales"},
+		{news.Sports, "deportes"},
+		{news.Entertainment, "entretenimiento"},
+		{news.Business, "negocios"},
+		{news.Science, "ciencia-ambiente"},
+		{news.Technology, "tecnologia"},
+	}
+
+	client := e.client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
 	}
 
-	allArticles := []news.Article{}
+	var fetched [][]news.Article
 
-	for topic, feedURL := range feeds {
-		articles, err := e.fetchFromFeed(client, feedURL, topic)
+	for _, feed := range feeds {
+		articles, err := e.fetchFromFeed(client, fmt.Sprintf(baseURL, feed.category), feed.topic)
 		if err != nil {
 			fmt.Printf("Warning: Failed to fetch feed: %v\n", err)
 			continue
 		}
-		allArticles = append(allArticles, articles...)
+
+		for i := range articles {
+			articles[i].Feed = feed.category
+		}
+		fetched = append(fetched, articles)
 
 	}
 
-	if len(allArticles) > MaxArticles {
-		allArticles = allArticles[:MaxArticles]
+	return spread(fetched, MaxArticles), nil
+}
+
+// spread takes up to limit articles from the feeds, one from each feed in turn, so the last feeds
+// aren't left out when the first ones fill the limit. The articles of each feed stay together, in
+// the order of the feeds.
+func spread(feeds [][]news.Article, limit int) []news.Article {
+	counts := make([]int, len(feeds))
+	for total, more := 0, true; more; {
+		more = false
+		for i := range feeds {
+			if total < limit && counts[i] < len(feeds[i]) {
+				counts[i]++
+				total++
+				more = true
+			}
+		}
 	}
 
-	return allArticles, nil
+	articles := []news.Article{}
+	for i, feed := range feeds {
+		articles = append(articles, feed[:counts[i]]...)
+	}
+	return articles
 }
 func (e *Endi) fetchFromFeed(client *http.Client, feedURL string, topic news.Topic) ([]news.Article, error) {
 	resp, err := e.makeRequest(client, feedURL)
@@ -134,10 +167,6 @@ func (e *Endi) convertRSSToArticles(rss *RSS, topic news.Topic) []news.Article {
 		}
 
 		title := strings.TrimSpace(item.Title)
-		if isDuplicate(title, e.oldArticleTitles) {
-			continue
-		}
-
 		article := e.createArticleFromItem(item, topic, title)
 		articles = append(articles, article)
 	}
@@ -156,6 +185,17 @@ func (e *Endi) createArticleFromItem(item Item, topic news.Topic, title string)
 		},
 	}
 
+	article.Author = strings.TrimSpace(item.Creator)
+	for _, category := range item.Categories {
+		if category = strings.TrimSpace(category); category != "" {
+			article.Categories = append(article.Categories, category)
+		}
+	}
+
+	if published, err := time.Parse(time.RFC1123Z, strings.TrimSpace(item.PubDate)); err == nil {
+		article.Published = published
+	}
+
 	if item.Description != "" {
 		content := cleanDescription(item.Description)
 		article.Content = &content
@@ -172,7 +212,7 @@ func (e *Endi) createArticleFromItem(item Item, topic news.Topic, title string)
 }
 
 func (e *Endi) createThumbnail(mediaContent MediaContent, fallbackCaption string) *news.Thumbnail {
-	imageData, err := news.DownloadImage(mediaContent.URL)
+	imageData, err := news.DownloadImage(mediaContent.URL, e.client)
 	if err != nil || len(imageData) == 0 {
 		return nil
 	}
@@ -193,15 +233,6 @@ func (e *Endi) createThumbnail(mediaContent MediaContent, fallbackCaption string
 	}
 }
 
-func isDuplicate(title string, oldTitles []string) bool {
-	for _, oldTitle := range oldTitles {
-		if strings.EqualFold(title, oldTitle) {
-			return true
-		}
-	}
-	return false
-}
-
 func cleanDescription(description string) string {
 	description = html.UnescapeString(description)
 
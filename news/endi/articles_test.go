@@ -0,0 +1,171 @@
+package endi
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	localesPath  = "/arc/outboundfeeds/rss/category/noticias/locales/"
+	deportesPath = "/arc/outboundfeeds/rss/category/deportes/"
+)
+
+func newFixtureEndi(t *testing.T) (*Endi, *newstest.FeedServer) {
+	t.Helper()
+
+	server := newstest.NewFeedServer("testdata")
+	t.Cleanup(server.Close)
+
+	server.Route(localesPath, "locales.xml")
+	server.Route(deportesPath, "deportes.xml")
+
+	e := NewEndi(
+		WithBaseURL(server.URL),
+		WithHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}),
+	)
+
+	return e, server
+}
+
+func TestGetArticlesFromFixtures(t *testing.T) {
+	e, server := newFixtureEndi(t)
+
+	articles, err := e.GetArticles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		title     string
+		topic     news.Topic
+		thumbnail bool
+	}{
+		{"Vuelven las lluvias al área metro", news.NationalNews, true},
+		{"Gobernadora firma ley de presupuesto", news.NationalNews, false},
+		// The image of this article returns 404, so it is kept without one.
+		{"AAA anuncia interrupciones en Caguas", news.NationalNews, false},
+		{"Cangrejeros ganan el primer juego de la final", news.Sports, true},
+		{"Atletas boricuas se preparan para los Centroamericanos", news.Sports, false},
+	}
+
+	if len(articles) != len(want) {
+		t.Fatalf("got %d articles, want %d", len(articles), len(want))
+	}
+
+	for i, w := range want {
+		article := articles[i]
+		if article.Title != w.title || article.Topic != w.topic {
+			t.Errorf("article %d = %q (topic %d), want %q (topic %d)", i, article.Title, article.Topic, w.title, w.topic)
+		}
+
+		if (article.Thumbnail != nil) != w.thumbnail {
+			t.Errorf("article %d has thumbnail = %v, want %v", i, article.Thumbnail != nil, w.thumbnail)
+		}
+
+		if article.Content == nil || strings.Contains(*article.Content, "<") {
+			t.Errorf("article %d content was not cleaned: %v", i, article.Content)
+		}
+	}
+
+	if want := time.Date(2024, time.September, 1, 17, 45, 0, 0, time.UTC); !articles[0].Published.Equal(want) {
+		t.Errorf("published = %s, want %s", articles[0].Published, want)
+	}
+
+	if a := articles[0]; a.Feed != "noticias/locales" || a.Author != "Redacción de El Nuevo Día" || len(a.Categories) != 1 || a.Categories[0] != "Clima" {
+		t.Errorf("feed %q, author %q, categories %q", a.Feed, a.Author, a.Categories)
+	}
+	if articles[3].Feed != "deportes" {
+		t.Errorf("feed = %q, want deportes", articles[3].Feed)
+	}
+
+	if got := articles[0].Thumbnail.Caption; got != "Calles inundadas en Río Piedras." {
+		t.Errorf("caption = %q", got)
+	}
+
+	// Without a media description the title is used as the caption.
+	if got := articles[3].Thumbnail.Caption; got != articles[3].Title {
+		t.Errorf("fallback caption = %q", got)
+	}
+
+	if got := *articles[1].Content; got != "El presupuesto de $13,100 millones entra en vigor el 1 de julio. La Legislatura lo aprobó la semana pasada." {
+		t.Errorf("content = %q", got)
+	}
+
+	// Every feed is requested, even the ones without fixtures.
+	feeds := 0
+	for _, path := range server.Requests() {
+		if strings.HasPrefix(path, "/arc/outboundfeeds/rss/category/") {
+			feeds++
+		}
+	}
+	if feeds != 6 {
+		t.Errorf("requested %d feeds, want 6", feeds)
+	}
+}
+
+func TestFetchFromFeedFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault newstest.Fault
+		err   string
+	}{
+		{"not found", newstest.FaultNotFound, "status code: 404"},
+		{"server error", newstest.FaultServerError, "status code: 500"},
+		{"slow", newstest.FaultSlow, "failed to fetch RSS"},
+		{"truncated", newstest.FaultTruncated, "failed to read RSS response"},
+		{"malformed", newstest.FaultMalformed, "failed to parse RSS XML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, server := newFixtureEndi(t)
+			server.Fail(localesPath, tt.fault)
+
+			_, err := e.fetchFromFeed(e.client, server.URL+localesPath, news.NationalNews)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("err = %v, want it to contain %q", err, tt.err)
+			}
+
+			// A broken feed only drops its own articles.
+			articles, err := e.GetArticles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(articles) != 2 || articles[0].Topic != news.Sports {
+				t.Errorf("got %d articles, want the 2 sports articles", len(articles))
+			}
+		})
+	}
+}
+
+func TestSpread(t *testing.T) {
+	// Six full feeds are more than MaxArticles, and the last one still gets its share.
+	var feeds [][]news.Article
+	for topic := news.NationalNews; topic <= news.Technology; topic++ {
+		feeds = append(feeds, make([]news.Article, MaxArticlesPerCategory))
+		for i := range feeds[len(feeds)-1] {
+			feeds[len(feeds)-1][i] = news.Article{Title: fmt.Sprintf("%d-%d", topic, i), Topic: topic}
+		}
+	}
+
+	articles := spread(feeds, MaxArticles)
+	if len(articles) != MaxArticles {
+		t.Fatalf("got %d articles, want %d", len(articles), MaxArticles)
+	}
+
+	perTopic := map[news.Topic]int{}
+	for _, article := range articles {
+		perTopic[article.Topic]++
+	}
+	if perTopic[news.NationalNews] != 3 || perTopic[news.Technology] != 2 {
+		t.Errorf("articles per topic = %v, want 3 national and 2 technology", perTopic)
+	}
+	if articles[0].Title != "0-0" || articles[len(articles)-1].Title != fmt.Sprintf("%d-1", news.Technology) {
+		t.Errorf("first %q, last %q", articles[0].Title, articles[len(articles)-1].Title)
+	}
+}
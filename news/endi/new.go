@@ -1,11 +1,41 @@
 package endi
 
+import "net/http"
+
+// DefaultBaseURL is where El Nuevo Día serves its RSS feeds.
+const DefaultBaseURL = "https://www.elnuevodia.com"
+
 type Endi struct {
-	oldArticleTitles []string
+	baseURL string
+	// client is nil unless replaced, in which case feeds and images use their own default timeouts.
+	client *http.Client
+}
+
+// Option customises an Endi source.
+type Option func(*Endi)
+
+// WithBaseURL fetches the feeds from another host, such as a local fixture server.
+func WithBaseURL(baseURL string) Option {
+	return func(e *Endi) {
+		e.baseURL = baseURL
+	}
 }
 
-func NewEndi(oldArticleTitles []string) *Endi {
-	return &Endi{
-		oldArticleTitles: oldArticleTitles,
+// WithHTTPClient replaces the client used for feeds and images.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Endi) {
+		e.client = client
 	}
 }
+
+func NewEndi(opts ...Option) *Endi {
+	e := &Endi{
+		baseURL: DefaultBaseURL,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
@@ -0,0 +1,144 @@
+package manual
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fields are what an article file can set. The times are kept as written and parsed as RFC 3339,
+// so a date without a time or zone is rejected rather than taken as UTC midnight.
+type fields struct {
+	Title     string   `yaml:"title"`
+	Headline  string   `yaml:"headline"`
+	Author    string   `yaml:"author"`
+	Body      string   `yaml:"body"`
+	Topic     string   `yaml:"topic"`
+	Location  string   `yaml:"location"`
+	Latitude  *float64 `yaml:"latitude"`
+	Longitude *float64 `yaml:"longitude"`
+	Image     string   `yaml:"image"`
+	Caption   string   `yaml:"caption"`
+	From      string   `yaml:"from"`
+	Until     string   `yaml:"until"`
+	Pinned    bool     `yaml:"pinned"`
+}
+
+// parseFields reads an article written as a YAML mapping. Unknown keys are rejected, so a
+// misspelt field isn't silently ignored.
+func parseFields(data []byte) (fields, error) {
+	var f fields
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return fields{}, err
+	}
+
+	f.Body = strings.TrimSpace(f.Body)
+	return f, nil
+}
+
+// parseMarkdown reads a Markdown file whose front matter, between two "---" lines, holds the
+// fields. The rest of the file is the body, as plain text.
+func parseMarkdown(data []byte) (fields, error) {
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	if !scanner.Scan() || strings.TrimSpace(scanner.Text()) != "---" {
+		return fields{}, fmt.Errorf("missing front matter")
+	}
+
+	var frontMatter, body []string
+	inFrontMatter := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if inFrontMatter && strings.TrimSpace(line) == "---" {
+			inFrontMatter = false
+			continue
+		}
+
+		if inFrontMatter {
+			frontMatter = append(frontMatter, line)
+		} else {
+			body = append(body, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fields{}, err
+	}
+	if inFrontMatter {
+		return fields{}, fmt.Errorf("front matter is not closed with ---")
+	}
+
+	f, err := parseFields([]byte(strings.Join(frontMatter, "\n")))
+	if err != nil {
+		return fields{}, fmt.Errorf("front matter: %w", err)
+	}
+
+	if f.Body != "" {
+		return fields{}, fmt.Errorf("front matter: the body is the text after it")
+	}
+	f.Body = plainText(strings.Join(body, "\n"))
+
+	return f, nil
+}
+
+var (
+	paragraphBreak   = regexp.MustCompile(`\n\s*\n`)
+	markdownImage    = regexp.MustCompile(`!\[[^\]]*\]\([^)]*\)`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownEmphasis = regexp.MustCompile(`(\*\*|__|\*|_|~~|` + "`" + `)(\S(?:.*?\S)?)(\*\*|__|\*|_|~~|` + "`" + `)`)
+	markdownHeading  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	markdownQuote    = regexp.MustCompile(`(?m)^>\s?`)
+	markdownBullet   = regexp.MustCompile(`(?m)^\s*[-*+]\s+`)
+)
+
+// plainText turns Markdown into the plain text the channel shows: links become their text,
+// images and emphasis markers are dropped, list items become bullets and the lines of a paragraph
+// are joined.
+func plainText(markdown string) string {
+	text := markdownImage.ReplaceAllString(markdown, "")
+	text = markdownLink.ReplaceAllString(text, "$1")
+	text = markdownEmphasis.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownEmphasis.FindStringSubmatch(match)
+		if parts[1] != parts[3] {
+			return match
+		}
+		return parts[2]
+	})
+	text = markdownHeading.ReplaceAllString(text, "")
+	text = markdownQuote.ReplaceAllString(text, "")
+
+	// List items stay on lines of their own.
+	var blocks []string
+	for _, paragraph := range paragraphBreak.Split(text, -1) {
+		if markdownBullet.MatchString(paragraph) {
+			var items []string
+			for i, item := range markdownBullet.Split(paragraph, -1) {
+				item = strings.Join(strings.Fields(item), " ")
+				switch {
+				case item == "":
+				case i == 0:
+					// Text before the first item.
+					items = append(items, item)
+				default:
+					items = append(items, "• "+item)
+				}
+			}
+			blocks = append(blocks, strings.Join(items, "\n"))
+			continue
+		}
+
+		if paragraph = strings.Join(strings.Fields(paragraph), " "); paragraph != "" {
+			blocks = append(blocks, paragraph)
+		}
+	}
+
+	return strings.Join(blocks, "\n\n")
+}
@@ -0,0 +1,241 @@
+// Package manual reads hand-written articles from a directory, for community announcements and
+// corrections that no feed carries. Every file is one article, in YAML or in Markdown with YAML
+// front matter:
+//
+//	---
+//	title: Centros de acopio abiertos este sábado
+//	headline: Centros de acopio abiertos
+//	topic: national
+//	location: Ponce
+//	image: acopio.jpg
+//	from: 2024-09-06T08:00:00-04:00
+//	until: 2024-09-08T20:00:00-04:00
+//	pinned: true
+//	---
+//	Los centros de acopio de la **Cruz Roja** abren de 8:00 a.m. a 4:00 p.m.
+//
+// A YAML file has the same fields, with the text under body. Only the title is required.
+package manual
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/nws"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Manual reads the articles in a directory on every run, so they can be added, changed or removed
+// while the generator keeps running.
+type Manual struct {
+	dir string
+	now func() time.Time
+}
+
+// Option customises a Manual source.
+type Option func(*Manual)
+
+// WithNow replaces the clock that decides which articles are within their publish window.
+func WithNow(now func() time.Time) Option {
+	return func(m *Manual) {
+		m.now = now
+	}
+}
+
+// NewManual reads the articles in dir.
+func NewManual(dir string, opts ...Option) *Manual {
+	m := &Manual{
+		dir: dir,
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Hand-written articles don't have a logo of their own.
+func (m *Manual) GetLogo() []byte {
+	return nil
+}
+
+// GetArticles returns the articles within their publish window, in the order of their file names.
+// A file that can't be read is skipped with a warning, so one mistake doesn't hold back the rest.
+func (m *Manual) GetArticles() ([]news.Article, error) {
+	files, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name() < files[j].Name()
+	})
+
+	now := m.now()
+	articles := []news.Article{}
+	for _, file := range files {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
+		path := filepath.Join(m.dir, file.Name())
+		article, window, err := ReadFile(path)
+		if errors.Is(err, errUnsupported) {
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: Skipping article %s: %v\n", path, err)
+			continue
+		}
+
+		if window.Contains(now) {
+			articles = append(articles, article)
+		}
+	}
+
+	return articles, nil
+}
+
+// Window is when an article is published. A zero From or Until leaves that side open.
+type Window struct {
+	From  time.Time
+	Until time.Time
+}
+
+// Contains reports whether t falls within the window.
+func (w Window) Contains(t time.Time) bool {
+	return (w.From.IsZero() || !t.Before(w.From)) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+var errUnsupported = errors.New("not an article file")
+
+// ReadFile reads the article in a .yaml, .yml, .md or .markdown file. Image paths are relative to
+// the file.
+func ReadFile(path string) (news.Article, Window, error) {
+	var format func([]byte) (fields, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = parseFields
+	case ".md", ".markdown":
+		format = parseMarkdown
+	default:
+		return news.Article{}, Window{}, errUnsupported
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return news.Article{}, Window{}, err
+	}
+
+	f, err := format(data)
+	if err != nil {
+		return news.Article{}, Window{}, err
+	}
+
+	return newArticle(f, filepath.Dir(path))
+}
+
+// newArticle builds an article from the fields of its file.
+func newArticle(f fields, dir string) (news.Article, Window, error) {
+	var window Window
+	article := news.Article{
+		Title:        f.Title,
+		MenuHeadline: f.Headline,
+		Feed:         "manual",
+		Author:       f.Author,
+		Pinned:       f.Pinned,
+	}
+
+	if article.Title == "" {
+		return news.Article{}, window, errors.New("missing title")
+	}
+
+	if body := f.Body; body != "" {
+		article.Content = &body
+	}
+
+	var err error
+	if f.Topic != "" {
+		if article.Topic, err = news.ParseTopic(f.Topic); err != nil {
+			return news.Article{}, window, err
+		}
+	}
+
+	if article.Location, err = location(f); err != nil {
+		return news.Article{}, window, err
+	}
+
+	if image := f.Image; image != "" {
+		if !filepath.IsAbs(image) {
+			image = filepath.Join(dir, image)
+		}
+
+		data, err := os.ReadFile(image)
+		if err != nil {
+			return news.Article{}, window, err
+		}
+
+		converted := news.ConvertImage(data)
+		if len(converted) == 0 {
+			return news.Article{}, window, fmt.Errorf("%s is not a JPEG or PNG image", image)
+		}
+
+		caption := f.Caption
+		if caption == "" {
+			caption = article.Title
+		}
+		article.Thumbnail = &news.Thumbnail{Image: converted, Caption: caption}
+	}
+
+	bounds := []struct {
+		name  string
+		text  string
+		value *time.Time
+	}{{"from", f.From, &window.From}, {"until", f.Until, &window.Until}}
+	for _, bound := range bounds {
+		if bound.text == "" {
+			continue
+		}
+		if *bound.value, err = time.Parse(time.RFC3339, bound.text); err != nil {
+			return news.Article{}, window, fmt.Errorf("invalid %s: %w", bound.name, err)
+		}
+	}
+
+	article.Published = window.From
+
+	return article, window, nil
+}
+
+// location places the article at its latitude and longitude, or at the municipality or place it
+// names. Without either it is in San Juan, like the feed articles.
+func location(f fields) (*news.Location, error) {
+	name := f.Location
+	if f.Latitude != nil || f.Longitude != nil {
+		if f.Latitude == nil || f.Longitude == nil {
+			return nil, errors.New("give both the latitude and the longitude")
+		}
+
+		return &news.Location{Name: name, Latitude: *f.Latitude, Longitude: *f.Longitude}, nil
+	}
+
+	if name == "" {
+		return &news.Location{Name: news.SanJuanName, Latitude: news.SanJuanLatitude, Longitude: news.SanJuanLongitude}, nil
+	}
+
+	if municipality, ok := nws.Municipality(name); ok {
+		return &municipality, nil
+	}
+
+	if place, ok := news.CommonLocations[strings.ToUpper(name)]; ok {
+		return &place, nil
+	}
+
+	return nil, fmt.Errorf("unknown location %q, give its latitude and longitude", name)
+}
@@ -0,0 +1,142 @@
+package manual
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/signing"
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetArticlesFromFixtures(t *testing.T) {
+	now := time.Date(2024, time.September, 1, 14, 30, 0, 0, time.UTC)
+	articles, err := NewManual("testdata/articles", WithNow(func() time.Time { return now })).GetArticles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The expired and future articles are outside their window, and the broken one is skipped.
+	if len(articles) != 2 {
+		t.Fatalf("got %d articles, want 2", len(articles))
+	}
+
+	acopio := articles[0]
+	if acopio.Title != "Centros de acopio abiertos este sábado" || !acopio.Pinned || acopio.Topic != news.NationalNews {
+		t.Errorf("article 0 = %+v", acopio)
+	}
+	if acopio.MenuHeadline != "Centros de acopio abiertos" || !acopio.Published.Equal(time.Date(2024, time.September, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("article 0 headline %q, published %s", acopio.MenuHeadline, acopio.Published)
+	}
+	if acopio.Location == nil || acopio.Location.Name != "Ponce" {
+		t.Errorf("article 0 location = %+v", acopio.Location)
+	}
+	if acopio.Thumbnail == nil || len(acopio.Thumbnail.Image) == 0 || acopio.Thumbnail.Caption != "Voluntarios en el centro de acopio de Ponce" {
+		t.Errorf("article 0 thumbnail = %+v", acopio.Thumbnail)
+	}
+
+	wantBody := "Centros de acopio\n\n" +
+		"Los centros de acopio de la Cruz Roja abren de 8:00 a.m. a 4:00 p.m. Más información en cruzroja.org.\n\n" +
+		"Se necesitan:\n\n" +
+		"• agua embotellada\n• baterías"
+	if acopio.Content == nil || *acopio.Content != wantBody {
+		t.Errorf("article 0 body = %q, want %q", *acopio.Content, wantBody)
+	}
+
+	correction := articles[1]
+	if correction.Title != "Corrección: la AAA restablece el servicio en Mayagüez" || correction.Pinned || correction.Topic != news.Business {
+		t.Errorf("article 1 = %+v", correction)
+	}
+	if correction.Location == nil || correction.Location.Name != "Mayagüez" {
+		t.Errorf("article 1 location = %+v", correction.Location)
+	}
+
+	wantBody = "La Autoridad de Acueductos y Alcantarillados restableció el servicio\na 12,000 clientes.\n\nUna versión anterior de esta noticia indicaba 1,200 clientes."
+	if correction.Content == nil || *correction.Content != wantBody {
+		t.Errorf("article 1 body = %q, want %q", *correction.Content, wantBody)
+	}
+}
+
+func TestGetArticlesMissingDirectory(t *testing.T) {
+	if _, err := NewManual("testdata/missing").GetArticles(); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	f, err := parseFields([]byte("title: \"Aviso: \\\"cierre\\\"\"\nlatitude: 18.2 # Mayagüez\nlongitude: -67.14\nfrom: 2024-09-06T08:00:00-04:00\npinned: true\nbody: >\n  Primera línea\n  sigue aquí.\n\n  Segundo párrafo.\ncaption: 'It''s open'\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	latitude, longitude := 18.2, -67.14
+	want := fields{
+		Title:     `Aviso: "cierre"`,
+		Latitude:  &latitude,
+		Longitude: &longitude,
+		From:      "2024-09-06T08:00:00-04:00",
+		Pinned:    true,
+		Body:      "Primera línea sigue aquí.\nSegundo párrafo.",
+		Caption:   "It's open",
+	}
+	if !reflect.DeepEqual(f, want) {
+		t.Errorf("fields = %+v, want %+v", f, want)
+	}
+
+	for _, invalid := range []string{"title", "title: a\ntitle: b", "titel: misspelt", "title: 'unterminated", "latitude: north", "pinned: maybe"} {
+		if _, err = parseFields([]byte(invalid)); err == nil {
+			t.Errorf("%q was accepted", invalid)
+		}
+	}
+}
+
+func TestWindow(t *testing.T) {
+	from := time.Date(2024, time.September, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		window Window
+		at     time.Time
+		want   bool
+	}{
+		{Window{}, from, true},
+		{Window{From: from}, from, true},
+		{Window{From: from}, from.Add(-time.Second), false},
+		{Window{Until: until}, until, false},
+		{Window{From: from, Until: until}, from.Add(time.Hour), true},
+	}
+
+	for _, test := range tests {
+		if got := test.window.Contains(test.at); got != test.want {
+			t.Errorf("%+v contains %s = %v, want %v", test.window, test.at, got, test.want)
+		}
+	}
+}
+
+func TestLocationReachesFile(t *testing.T) {
+	now := time.Date(2024, time.September, 1, 14, 30, 0, 0, time.UTC)
+	result, err := generator.Generate(context.Background(), generator.Options{
+		CacheDir: t.TempDir(),
+		Signer:   signing.Unsigned{},
+		Source:   NewManual("testdata/articles", WithNow(func() time.Time { return now })),
+		Clock:    generator.FixedClock(now),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := generator.DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each article is shown on the globe at the place its file names.
+	var places []string
+	for _, article := range parsed.Articles {
+		places = append(places, parsed.Locations[article.LocationIndex].Name)
+	}
+	if want := []string{"Ponce", "Mayagüez"}; !reflect.DeepEqual(places, want) {
+		t.Errorf("places = %q, want %q", places, want)
+	}
+}
@@ -0,0 +1,28 @@
+package news
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidMessage is returned by a MessageSource whose configured message can't be shown, such as
+// one without an expiry. Unlike a file or feed that can't be read, it fails the run, as it won't
+// fix itself.
+var ErrInvalidMessage = errors.New("invalid message")
+
+// MessageSource provides the urgent message shown at the top of the channel, such as a hurricane
+// warning. It returns nil when there is none.
+type MessageSource interface {
+	GetMessage() (*Message, error)
+}
+
+// Message is an urgent message, shown until it expires.
+type Message struct {
+	Text    string    `json:"text"`
+	Expires time.Time `json:"expires"`
+}
+
+// Active reports whether the message should still be shown at t.
+func (m *Message) Active(t time.Time) bool {
+	return m != nil && m.Text != "" && t.Before(m.Expires)
+}
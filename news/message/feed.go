@@ -0,0 +1,94 @@
+package message
+
+import (
+	"WiiNewsPR/news"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Feed takes the message from the newest entry of an RSS or Atom feed, such as an emergency
+// management office's alerts. The entry's title is the text, and it expires the lifetime after it
+// was published. An empty feed means no message.
+type Feed struct {
+	url      string
+	lifetime time.Duration
+	client   *http.Client
+}
+
+// NewFeed reads the message from the feed at url. A lifetime of zero means DefaultLifetime.
+func NewFeed(url string, lifetime time.Duration) *Feed {
+	if lifetime <= 0 {
+		lifetime = DefaultLifetime
+	}
+	return &Feed{url: url, lifetime: lifetime, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// feed holds the parts of an RSS or Atom document the message is taken from. Only one of Items and
+// Entries is filled.
+type feed struct {
+	Items   []entry `xml:"channel>item"`
+	Entries []entry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+type entry struct {
+	Title     string `xml:"title"`
+	PubDate   string `xml:"pubDate"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+}
+
+// date parses whichever publication date the entry has.
+func (e entry) date() (time.Time, bool) {
+	for _, value := range []string{e.PubDate, e.Published, e.Updated} {
+		for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339} {
+			if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func (f *Feed) GetMessage() (*news.Message, error) {
+	req, err := http.NewRequest(http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "WiiNewsPR/1.0 (+https://github.com/rnegron/WiiNewsPR)")
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml, text/xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message feed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("message feed returned status code: %d", resp.StatusCode)
+	}
+
+	var parsed feed
+	if err = xml.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse message feed: %w", err)
+	}
+
+	// Undated entries are skipped, as there is no telling when they should expire.
+	var newest *news.Message
+	var published time.Time
+	for _, item := range append(parsed.Items, parsed.Entries...) {
+		date, ok := item.date()
+		text := strings.TrimSpace(html.UnescapeString(item.Title))
+		if !ok || text == "" || (newest != nil && !date.After(published)) {
+			continue
+		}
+
+		published = date
+		newest = &news.Message{Text: text, Expires: date.Add(f.lifetime)}
+	}
+
+	return newest, nil
+}
@@ -0,0 +1,69 @@
+package message
+
+import (
+	"WiiNewsPR/news"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// File reads the message from a text file on every run, so it can be changed without restarting
+// anything. Deleting the file removes the message.
+//
+// The file may start with an expiry line, followed by a blank line and the text:
+//
+//	Expires: 2024-09-02T18:00:00-04:00
+//
+//	Hurricane warning in effect for all of Puerto Rico.
+//
+// Without one, the message expires the lifetime after the file was last modified.
+type File struct {
+	path     string
+	lifetime time.Duration
+}
+
+// NewFile reads the message from path. A lifetime of zero means DefaultLifetime.
+func NewFile(path string, lifetime time.Duration) *File {
+	if lifetime <= 0 {
+		lifetime = DefaultLifetime
+	}
+	return &File{path: path, lifetime: lifetime}
+}
+
+func (f *File) GetMessage() (*news.Message, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return nil, err
+	}
+
+	message := &news.Message{
+		Text:    strings.TrimSpace(string(data)),
+		Expires: info.ModTime().Add(f.lifetime),
+	}
+
+	first, rest, _ := strings.Cut(message.Text, "\n")
+	name, value, ok := strings.Cut(first, ":")
+	if ok && strings.EqualFold(strings.TrimSpace(name), "expires") {
+		message.Expires, err = time.Parse(time.RFC3339, strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid expiry: %w", f.path, err)
+		}
+		message.Text = strings.TrimSpace(rest)
+	}
+
+	if message.Text == "" {
+		return nil, nil
+	}
+	return message, nil
+}
@@ -0,0 +1,93 @@
+// Package message provides the urgent message shown at the top of the channel, from the
+// configuration, a local file or a feed. Every message expires, so a forgotten warning drops out of
+// the channel by itself.
+package message
+
+import (
+	"WiiNewsPR/news"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultLifetime is how long a message without an explicit expiry is shown after it was written
+// or published.
+const DefaultLifetime = 6 * time.Hour
+
+// Static is a fixed message, such as one given on the command line.
+type Static struct {
+	Message news.Message
+}
+
+// NewStatic shows text until expires.
+func NewStatic(text string, expires time.Time) *Static {
+	return &Static{Message: news.Message{Text: text, Expires: expires}}
+}
+
+func (s *Static) GetMessage() (*news.Message, error) {
+	if s.Message.Text == "" || s.Message.Expires.IsZero() {
+		return nil, fmt.Errorf("%w: a message needs a text and an expiry time", news.ErrInvalidMessage)
+	}
+
+	message := s.Message
+	return &message, nil
+}
+
+// Config picks where the message comes from. At most one of Text, File and FeedURL is set.
+type Config struct {
+	// Text is shown until Expires, which is required with it.
+	Text    string
+	Expires time.Time
+	// File is read on every run. See NewFile.
+	File string
+	// FeedURL is an RSS or Atom feed whose newest entry is the message. See NewFeed.
+	FeedURL string
+	// Lifetime is how long a message from File or FeedURL without an expiry is shown. Defaults to
+	// DefaultLifetime.
+	Lifetime time.Duration
+}
+
+// ConfigFromEnv reads WIINEWSPR_MESSAGE and WIINEWSPR_MESSAGE_EXPIRES (RFC 3339),
+// WIINEWSPR_MESSAGE_FILE or WIINEWSPR_MESSAGE_FEED.
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Text:    os.Getenv("WIINEWSPR_MESSAGE"),
+		File:    os.Getenv("WIINEWSPR_MESSAGE_FILE"),
+		FeedURL: os.Getenv("WIINEWSPR_MESSAGE_FEED"),
+	}
+
+	if expires := os.Getenv("WIINEWSPR_MESSAGE_EXPIRES"); expires != "" {
+		var err error
+		if config.Expires, err = time.Parse(time.RFC3339, expires); err != nil {
+			return Config{}, fmt.Errorf("invalid WIINEWSPR_MESSAGE_EXPIRES: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
+// Source returns the configured source, or nil when no message is configured.
+func (c Config) Source() (news.MessageSource, error) {
+	set := 0
+	for _, value := range []string{c.Text, c.File, c.FeedURL} {
+		if value != "" {
+			set++
+		}
+	}
+
+	switch {
+	case set > 1:
+		return nil, errors.New("only one of a message text, file or feed can be configured")
+	case c.Text != "" && c.Expires.IsZero():
+		return nil, errors.New("a message needs an expiry time")
+	case c.Text != "":
+		return NewStatic(c.Text, c.Expires), nil
+	case c.File != "":
+		return NewFile(c.File, c.Lifetime), nil
+	case c.FeedURL != "":
+		return NewFeed(c.FeedURL, c.Lifetime), nil
+	default:
+		return nil, nil
+	}
+}
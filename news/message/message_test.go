@@ -0,0 +1,115 @@
+package message
+
+import (
+	"WiiNewsPR/news"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "message.txt")
+	source := NewFile(path, time.Hour)
+
+	message, err := source.GetMessage()
+	if err != nil || message != nil {
+		t.Fatalf("missing file = %v, %v, want no message", message, err)
+	}
+
+	if err = os.WriteFile(path, []byte("Expires: 2024-09-02T18:00:00-04:00\n\nHurricane warning in effect.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err = source.GetMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "Hurricane warning in effect." || !message.Expires.Equal(time.Date(2024, time.September, 2, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("message = %+v", message)
+	}
+
+	modified := time.Date(2024, time.September, 1, 12, 0, 0, 0, time.UTC)
+	if err = os.WriteFile(path, []byte("Boil water advisory for Carolina\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err = source.GetMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Text != "Boil water advisory for Carolina" || !message.Expires.Equal(modified.Add(time.Hour)) {
+		t.Errorf("message without expiry = %+v", message)
+	}
+
+	if err = os.WriteFile(path, []byte("Expires: tomorrow\n\nText"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = source.GetMessage(); err == nil {
+		t.Error("invalid expiry was accepted")
+	}
+}
+
+func TestFeed(t *testing.T) {
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer server.Close()
+
+	tests := []struct {
+		file      string
+		text      string
+		published time.Time
+	}{
+		{"alerts.rss", "Hurricane Warning in effect for Puerto Rico & the Virgin Islands", time.Date(2024, time.September, 1, 17, 0, 0, 0, time.UTC)},
+		{"alerts.atom", "Flash Flood Warning for San Juan", time.Date(2024, time.September, 1, 16, 45, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		message, err := NewFeed(server.URL+"/"+test.file, 0).GetMessage()
+		if err != nil {
+			t.Fatalf("%s: %v", test.file, err)
+		}
+
+		if message.Text != test.text || !message.Expires.Equal(test.published.Add(DefaultLifetime)) {
+			t.Errorf("%s: message = %+v", test.file, message)
+		}
+	}
+
+	if _, err := NewFeed(server.URL+"/missing.rss", 0).GetMessage(); err == nil {
+		t.Error("missing feed did not fail")
+	}
+}
+
+func TestConfigSource(t *testing.T) {
+	expires := time.Date(2024, time.September, 2, 0, 0, 0, 0, time.UTC)
+
+	source, err := Config{}.Source()
+	if err != nil || source != nil {
+		t.Errorf("empty config = %v, %v, want no source", source, err)
+	}
+
+	source, err = Config{Text: "Curfew from 7pm", Expires: expires}.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message, _ := source.GetMessage(); message.Text != "Curfew from 7pm" || !message.Expires.Equal(expires) {
+		t.Errorf("message = %+v", message)
+	}
+
+	if _, err = (Config{Text: "Curfew from 7pm"}).Source(); err == nil {
+		t.Error("message without expiry was accepted")
+	}
+
+	if _, err = (Config{Text: "Curfew from 7pm", Expires: expires, FeedURL: "https://example.com/feed"}).Source(); err == nil {
+		t.Error("two message sources were accepted")
+	}
+
+	if _, err = NewStatic("Curfew from 7pm", time.Time{}).GetMessage(); !errors.Is(err, news.ErrInvalidMessage) {
+		t.Errorf("static message without expiry: err = %v, want ErrInvalidMessage", err)
+	}
+}
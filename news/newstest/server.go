@@ -0,0 +1,131 @@
+package newstest
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BaseURLPlaceholder is replaced with the server's URL in every file served, so recorded feeds
+// can point their image URLs back at the fixture server.
+const BaseURLPlaceholder = "{{BASE_URL}}"
+
+// Fault is a failure the fixture server can simulate for a path.
+type Fault int
+
+const (
+	// FaultNotFound responds with 404.
+	FaultNotFound Fault = iota + 1
+	// FaultServerError responds with 500.
+	FaultServerError
+	// FaultSlow waits for FeedServer.SlowDelay before serving the file.
+	FaultSlow
+	// FaultTruncated announces the full length but closes the connection halfway through the body.
+	FaultTruncated
+	// FaultMalformed serves bytes that are neither valid XML nor a valid image.
+	FaultMalformed
+)
+
+// FeedServer serves recorded responses from a directory and can simulate failures.
+type FeedServer struct {
+	*httptest.Server
+
+	// SlowDelay is how long FaultSlow waits. It defaults to one second.
+	SlowDelay time.Duration
+
+	dir string
+
+	mu       sync.Mutex
+	routes   map[string]string
+	faults   map[string]Fault
+	requests []string
+}
+
+// NewFeedServer starts a server for the files in dir. Close it when done.
+func NewFeedServer(dir string) *FeedServer {
+	s := &FeedServer{
+		SlowDelay: time.Second,
+		dir:       dir,
+		routes:    map[string]string{},
+		faults:    map[string]Fault{},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Route serves file, relative to the server's directory, for requests to path. Paths without
+// a route are looked up directly in the directory.
+func (s *FeedServer) Route(path, file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes[path] = file
+}
+
+// Fail makes requests to path fail with fault.
+func (s *FeedServer) Fail(path string, fault Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.faults[path] = fault
+}
+
+// Requests returns the paths requested so far.
+func (s *FeedServer) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.requests...)
+}
+
+func (s *FeedServer) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	fault := s.faults[r.URL.Path]
+	file, routed := s.routes[r.URL.Path]
+	s.mu.Unlock()
+
+	if !routed {
+		file = filepath.FromSlash(r.URL.Path)
+	}
+
+	switch fault {
+	case FaultNotFound:
+		http.NotFound(w, r)
+		return
+	case FaultServerError:
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	case FaultMalformed:
+		_, _ = w.Write([]byte("<rss><channel><item><title>unterminated"))
+		return
+	case FaultSlow:
+		select {
+		case <-time.After(s.SlowDelay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.dir, file))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	data = bytes.ReplaceAll(data, []byte(BaseURLPlaceholder), []byte(s.URL))
+
+	if fault == FaultTruncated {
+		// Writing less than the announced length makes the server drop the connection.
+		w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+		_, _ = w.Write(data[:len(data)/2])
+		return
+	}
+
+	_, _ = w.Write(data)
+}
@@ -0,0 +1,46 @@
+// Package newstest provides an in-memory news.Source for tests.
+package newstest
+
+import (
+	"WiiNewsPR/news"
+	"encoding/json"
+	"os"
+)
+
+// Source returns a fixed set of articles.
+type Source struct {
+	Articles []news.Article
+	Logo     []byte
+	// Err, when set, is returned by GetArticles instead of the articles.
+	Err error
+}
+
+func (s *Source) GetArticles() ([]news.Article, error) {
+	if s.Err != nil {
+		return nil, s.Err
+	}
+
+	// Hand out a copy so the caller can't modify the fixture.
+	articles := make([]news.Article, len(s.Articles))
+	copy(articles, s.Articles)
+	return articles, nil
+}
+
+func (s *Source) GetLogo() []byte {
+	return s.Logo
+}
+
+// LoadArticles reads a JSON encoded list of articles.
+func LoadArticles(path string) ([]news.Article, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var articles []news.Article
+	if err = json.Unmarshal(data, &articles); err != nil {
+		return nil, err
+	}
+
+	return articles, nil
+}
@@ -0,0 +1,103 @@
+package nws
+
+import (
+	"WiiNewsPR/news"
+	"strings"
+)
+
+// Municipalities maps the SAME code of each of Puerto Rico's 78 municipalities, which is its FIPS
+// county code, to where it is. The coordinates are those of the town centre.
+var Municipalities = map[string]news.Location{
+	"072001": {Name: "Adjuntas", Latitude: 18.163, Longitude: -66.722},
+	"072003": {Name: "Aguada", Latitude: 18.379, Longitude: -67.188},
+	"072005": {Name: "Aguadilla", Latitude: 18.427, Longitude: -67.154},
+	"072007": {Name: "Aguas Buenas", Latitude: 18.257, Longitude: -66.103},
+	"072009": {Name: "Aibonito", Latitude: 18.140, Longitude: -66.266},
+	"072011": {Name: "Añasco", Latitude: 18.283, Longitude: -67.140},
+	"072013": {Name: "Arecibo", Latitude: 18.472, Longitude: -66.716},
+	"072015": {Name: "Arroyo", Latitude: 17.966, Longitude: -66.061},
+	"072017": {Name: "Barceloneta", Latitude: 18.451, Longitude: -66.539},
+	"072019": {Name: "Barranquitas", Latitude: 18.186, Longitude: -66.306},
+	"072021": {Name: "Bayamón", Latitude: 18.399, Longitude: -66.156},
+	"072023": {Name: "Cabo Rojo", Latitude: 18.087, Longitude: -67.146},
+	"072025": {Name: "Caguas", Latitude: 18.234, Longitude: -66.035},
+	"072027": {Name: "Camuy", Latitude: 18.484, Longitude: -66.845},
+	"072029": {Name: "Canóvanas", Latitude: 18.379, Longitude: -65.901},
+	"072031": {Name: "Carolina", Latitude: 18.381, Longitude: -65.957},
+	"072033": {Name: "Cataño", Latitude: 18.441, Longitude: -66.118},
+	"072035": {Name: "Cayey", Latitude: 18.112, Longitude: -66.166},
+	"072037": {Name: "Ceiba", Latitude: 18.264, Longitude: -65.648},
+	"072039": {Name: "Ciales", Latitude: 18.336, Longitude: -66.469},
+	"072041": {Name: "Cidra", Latitude: 18.176, Longitude: -66.161},
+	"072043": {Name: "Coamo", Latitude: 18.080, Longitude: -66.358},
+	"072045": {Name: "Comerío", Latitude: 18.218, Longitude: -66.226},
+	"072047": {Name: "Corozal", Latitude: 18.342, Longitude: -66.317},
+	"072049": {Name: "Culebra", Latitude: 18.303, Longitude: -65.301},
+	"072051": {Name: "Dorado", Latitude: 18.459, Longitude: -66.268},
+	"072053": {Name: "Fajardo", Latitude: 18.326, Longitude: -65.652},
+	"072054": {Name: "Florida", Latitude: 18.363, Longitude: -66.572},
+	"072055": {Name: "Guánica", Latitude: 17.972, Longitude: -66.908},
+	"072057": {Name: "Guayama", Latitude: 17.984, Longitude: -66.114},
+	"072059": {Name: "Guayanilla", Latitude: 18.019, Longitude: -66.792},
+	"072061": {Name: "Guaynabo", Latitude: 18.357, Longitude: -66.111},
+	"072063": {Name: "Gurabo", Latitude: 18.254, Longitude: -65.973},
+	"072065": {Name: "Hatillo", Latitude: 18.486, Longitude: -66.825},
+	"072067": {Name: "Hormigueros", Latitude: 18.140, Longitude: -67.127},
+	"072069": {Name: "Humacao", Latitude: 18.150, Longitude: -65.827},
+	"072071": {Name: "Isabela", Latitude: 18.500, Longitude: -67.024},
+	"072073": {Name: "Jayuya", Latitude: 18.219, Longitude: -66.592},
+	"072075": {Name: "Juana Díaz", Latitude: 18.053, Longitude: -66.507},
+	"072077": {Name: "Juncos", Latitude: 18.228, Longitude: -65.921},
+	"072079": {Name: "Lajas", Latitude: 18.050, Longitude: -67.059},
+	"072081": {Name: "Lares", Latitude: 18.295, Longitude: -66.878},
+	"072083": {Name: "Las Marías", Latitude: 18.251, Longitude: -66.992},
+	"072085": {Name: "Las Piedras", Latitude: 18.183, Longitude: -65.866},
+	"072087": {Name: "Loíza", Latitude: 18.432, Longitude: -65.880},
+	"072089": {Name: "Luquillo", Latitude: 18.373, Longitude: -65.717},
+	"072091": {Name: "Manatí", Latitude: 18.428, Longitude: -66.492},
+	"072093": {Name: "Maricao", Latitude: 18.181, Longitude: -66.980},
+	"072095": {Name: "Maunabo", Latitude: 18.007, Longitude: -65.899},
+	"072097": {Name: "Mayagüez", Latitude: 18.201, Longitude: -67.140},
+	"072099": {Name: "Moca", Latitude: 18.395, Longitude: -67.113},
+	"072101": {Name: "Morovis", Latitude: 18.326, Longitude: -66.407},
+	"072103": {Name: "Naguabo", Latitude: 18.212, Longitude: -65.735},
+	"072105": {Name: "Naranjito", Latitude: 18.301, Longitude: -66.245},
+	"072107": {Name: "Orocovis", Latitude: 18.227, Longitude: -66.391},
+	"072109": {Name: "Patillas", Latitude: 18.004, Longitude: -66.013},
+	"072111": {Name: "Peñuelas", Latitude: 18.056, Longitude: -66.722},
+	"072113": {Name: "Ponce", Latitude: 18.011, Longitude: -66.614},
+	"072115": {Name: "Quebradillas", Latitude: 18.474, Longitude: -66.939},
+	"072117": {Name: "Rincón", Latitude: 18.340, Longitude: -67.250},
+	"072119": {Name: "Río Grande", Latitude: 18.380, Longitude: -65.831},
+	"072121": {Name: "Sabana Grande", Latitude: 18.078, Longitude: -66.961},
+	"072123": {Name: "Salinas", Latitude: 17.977, Longitude: -66.298},
+	"072125": {Name: "San Germán", Latitude: 18.083, Longitude: -67.045},
+	"072127": {Name: news.SanJuanName, Latitude: news.SanJuanLatitude, Longitude: news.SanJuanLongitude},
+	"072129": {Name: "San Lorenzo", Latitude: 18.190, Longitude: -65.961},
+	"072131": {Name: "San Sebastián", Latitude: 18.337, Longitude: -66.990},
+	"072133": {Name: "Santa Isabel", Latitude: 17.966, Longitude: -66.405},
+	"072135": {Name: "Toa Alta", Latitude: 18.388, Longitude: -66.248},
+	"072137": {Name: "Toa Baja", Latitude: 18.444, Longitude: -66.254},
+	"072139": {Name: "Trujillo Alto", Latitude: 18.355, Longitude: -66.007},
+	"072141": {Name: "Utuado", Latitude: 18.266, Longitude: -66.701},
+	"072143": {Name: "Vega Alta", Latitude: 18.412, Longitude: -66.331},
+	"072145": {Name: "Vega Baja", Latitude: 18.444, Longitude: -66.387},
+	"072147": {Name: "Vieques", Latitude: 18.149, Longitude: -65.443},
+	"072149": {Name: "Villalba", Latitude: 18.127, Longitude: -66.492},
+	"072151": {Name: "Yabucoa", Latitude: 18.050, Longitude: -65.879},
+	"072153": {Name: "Yauco", Latitude: 18.035, Longitude: -66.850},
+}
+
+// unaccent drops the accents of Spanish letters, so names match however they were typed.
+var unaccent = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// Municipality finds a municipality by name, ignoring case and accents.
+func Municipality(name string) (news.Location, bool) {
+	name = unaccent.Replace(strings.ToLower(strings.TrimSpace(name)))
+	for _, location := range Municipalities {
+		if unaccent.Replace(strings.ToLower(location.Name)) == name {
+			return location, true
+		}
+	}
+	return news.Location{}, false
+}
@@ -0,0 +1,311 @@
+// Package nws turns the active watches, warnings and advisories of the National Weather Service
+// office in San Juan into articles, so hurricane and flood alerts lead the channel.
+package nws
+
+import (
+	"WiiNewsPR/news"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the Atom feed of the alerts active in Puerto Rico, with the CAP fields of each.
+const DefaultURL = "https://api.weather.gov/alerts/active.atom?area=PR"
+
+// MaxAlerts is how many alerts are turned into articles, most important first.
+const MaxAlerts = 5
+
+// zonePrefix marks the UGC zones of Puerto Rico forecast by the San Juan office. Alerts only for
+// the Virgin Islands or coastal waters don't have one.
+const zonePrefix = "PRZ"
+
+// AtlanticStandardTime is Puerto Rico's time, used for the times in the articles. It has no
+// daylight saving time.
+var AtlanticStandardTime = time.FixedZone("AST", -4*60*60)
+
+// NWS reads alerts from a CAP Atom feed.
+type NWS struct {
+	url string
+	// client is nil unless replaced, in which case a client with a 30 second timeout is used.
+	client *http.Client
+	now    func() time.Time
+}
+
+// Option customises an NWS source.
+type Option func(*NWS)
+
+// WithURL reads the alerts from another feed, such as recorded CAP XML served by a test.
+func WithURL(url string) Option {
+	return func(n *NWS) {
+		n.url = url
+	}
+}
+
+// WithHTTPClient replaces the client used for the feed.
+func WithHTTPClient(client *http.Client) Option {
+	return func(n *NWS) {
+		n.client = client
+	}
+}
+
+// WithNow replaces the clock that decides which alerts expired.
+func WithNow(now func() time.Time) Option {
+	return func(n *NWS) {
+		n.now = now
+	}
+}
+
+func NewNWS(opts ...Option) *NWS {
+	n := &NWS{
+		url: DefaultURL,
+		now: time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	return n
+}
+
+// The NWS has no logo the channel could show, so the other sources' logo is used.
+func (n *NWS) GetLogo() []byte {
+	return nil
+}
+
+// Feed is an Atom feed of CAP alerts.
+type Feed struct {
+	Entries []Entry `xml:"entry"`
+}
+
+// Entry is an alert, with the CAP fields the articles are built from.
+type Entry struct {
+	Title     string  `xml:"title"`
+	Summary   string  `xml:"summary"`
+	Event     string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 event"`
+	Effective string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 effective"`
+	Expires   string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 expires"`
+	Status    string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 status"`
+	MsgType   string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 msgType"`
+	Urgency   string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 urgency"`
+	Severity  string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 severity"`
+	AreaDesc  string  `xml:"urn:oasis:names:tc:emergency:cap:1.2 areaDesc"`
+	Geocode   Geocode `xml:"urn:oasis:names:tc:emergency:cap:1.2 geocode"`
+}
+
+// Geocode lists the codes of the areas an alert covers, as valueName and value pairs.
+type Geocode struct {
+	Names  []string `xml:"valueName"`
+	Values []string `xml:"value"`
+}
+
+// Codes returns the codes of the given kind, such as "UGC" or "SAME". A value may hold several
+// codes separated by spaces.
+func (g Geocode) Codes(name string) []string {
+	var codes []string
+	for i, valueName := range g.Names {
+		if valueName == name && i < len(g.Values) {
+			codes = append(codes, strings.Fields(g.Values[i])...)
+		}
+	}
+	return codes
+}
+
+// severities ranks the CAP severities, most important first.
+var severities = map[string]int{"Extreme": 0, "Severe": 1, "Moderate": 2, "Minor": 3}
+
+// urgencies ranks the CAP urgencies, most pressing first.
+var urgencies = map[string]int{"Immediate": 0, "Expected": 1, "Future": 2, "Past": 3}
+
+// rank orders values missing from ranks, such as "Unknown", last.
+func rank(ranks map[string]int, value string) int {
+	if r, ok := ranks[value]; ok {
+		return r
+	}
+	return len(ranks)
+}
+
+func (n *NWS) GetArticles() ([]news.Article, error) {
+	client := n.client
+	if client == nil {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, n.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	// api.weather.gov rejects requests without a User-Agent that identifies the application.
+	req.Header.Set("User-Agent", "WiiNewsPR/1.0 (+https://github.com/rnegron/WiiNewsPR)")
+	req.Header.Set("Accept", "application/atom+xml")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch alerts: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("alerts feed returned status code: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alerts: %v", err)
+	}
+
+	var feed Feed
+	if err = xml.Unmarshal(body, &feed); err != nil {
+		return nil, fmt.Errorf("failed to parse alerts XML: %v", err)
+	}
+
+	return n.convertAlerts(feed.Entries), nil
+}
+
+// alert is an entry that is in effect, with its times parsed.
+type alert struct {
+	Entry
+	effective time.Time
+	expires   time.Time
+}
+
+// convertAlerts turns the alerts in effect for Puerto Rico into articles, the most severe and
+// urgent first and the newest first among equals.
+func (n *NWS) convertAlerts(entries []Entry) []news.Article {
+	now := n.now()
+
+	var alerts []alert
+	for _, entry := range entries {
+		if entry.Status != "Actual" || entry.MsgType == "Cancel" || !hasZone(entry.Geocode.Codes("UGC")) {
+			continue
+		}
+
+		expires, err := time.Parse(time.RFC3339, strings.TrimSpace(entry.Expires))
+		if err != nil || !expires.After(now) {
+			continue
+		}
+
+		effective, err := time.Parse(time.RFC3339, strings.TrimSpace(entry.Effective))
+		if err != nil {
+			effective = now
+		}
+
+		alerts = append(alerts, alert{Entry: entry, effective: effective, expires: expires})
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		a, b := alerts[i], alerts[j]
+		if rank(severities, a.Severity) != rank(severities, b.Severity) {
+			return rank(severities, a.Severity) < rank(severities, b.Severity)
+		}
+		if rank(urgencies, a.Urgency) != rank(urgencies, b.Urgency) {
+			return rank(urgencies, a.Urgency) < rank(urgencies, b.Urgency)
+		}
+		return a.effective.After(b.effective)
+	})
+
+	if len(alerts) > MaxAlerts {
+		alerts = alerts[:MaxAlerts]
+	}
+
+	articles := []news.Article{}
+	for _, alert := range alerts {
+		articles = append(articles, alert.article())
+	}
+	return articles
+}
+
+func hasZone(zones []string) bool {
+	for _, zone := range zones {
+		if strings.HasPrefix(zone, zonePrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// municipalities returns the locations of the municipalities the alert covers, in the order the
+// alert lists them.
+func (a alert) municipalities() []news.Location {
+	var locations []news.Location
+	seen := map[string]bool{}
+	for _, code := range a.Geocode.Codes("SAME") {
+		location, ok := Municipalities[code]
+		if ok && !seen[code] {
+			seen[code] = true
+			locations = append(locations, location)
+		}
+	}
+	return locations
+}
+
+func (a alert) article() news.Article {
+	locations := a.municipalities()
+
+	var names []string
+	for _, location := range locations {
+		names = append(names, location.Name)
+	}
+
+	title := strings.TrimSpace(a.Event)
+	if title == "" {
+		title = strings.TrimSpace(a.Title)
+	}
+	if len(names) > 0 {
+		title += " for " + summarizeNames(names, 3)
+	}
+
+	content := strings.Join(strings.Fields(a.Summary), " ")
+	if len(names) > 0 {
+		content += "\n\nMunicipalities: " + strings.Join(names, ", ") + "."
+	} else if area := strings.TrimSpace(a.AreaDesc); area != "" {
+		content += "\n\nAreas: " + strings.ReplaceAll(area, ";", ",") + "."
+	}
+	content += fmt.Sprintf("\n\nIn effect until %s.", a.expires.In(AtlanticStandardTime).Format("Monday, January 2 at 3:04 PM MST"))
+	content = strings.TrimSpace(content)
+
+	article := news.Article{
+		Title:        title,
+		MenuHeadline: strings.TrimSpace(a.Event),
+		Content:      &content,
+		Topic:        news.NationalNews,
+		Published:    a.effective,
+		Feed:         "nws",
+		Categories:   []string{strings.TrimSpace(a.Event)},
+		// A severe alert is a threat to life or property, so it leads the channel and the Wii
+		// Menu however much newer the other articles are.
+		Pinned: rank(severities, a.Severity) <= severities["Severe"],
+		Location: &news.Location{
+			Name:      news.SanJuanName,
+			Latitude:  news.SanJuanLatitude,
+			Longitude: news.SanJuanLongitude,
+		},
+	}
+
+	if len(locations) > 0 {
+		main := locations[0]
+		article.Location = &main
+		article.Locations = locations
+	}
+
+	return article
+}
+
+// summarizeNames lists up to limit names, and how many more there are.
+func summarizeNames(names []string, limit int) string {
+	switch {
+	case len(names) == 1:
+		return names[0]
+	case len(names) <= limit:
+		return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+	default:
+		return fmt.Sprintf("%s and %d more", strings.Join(names[:limit], ", "), len(names)-limit)
+	}
+}
@@ -0,0 +1,153 @@
+package nws
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/signing"
+	"context"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const alertsPath = "/alerts/active.atom"
+
+func newFixtureNWS(t *testing.T, now time.Time) (*NWS, *newstest.FeedServer) {
+	t.Helper()
+
+	server := newstest.NewFeedServer("testdata")
+	t.Cleanup(server.Close)
+
+	server.Route(alertsPath, "active.atom")
+
+	n := NewNWS(
+		WithURL(server.URL+alertsPath),
+		WithHTTPClient(&http.Client{Timeout: 200 * time.Millisecond}),
+		WithNow(func() time.Time { return now }),
+	)
+
+	return n, server
+}
+
+func TestGetArticlesFromFixture(t *testing.T) {
+	n, _ := newFixtureNWS(t, time.Date(2024, time.September, 1, 14, 5, 0, 0, AtlanticStandardTime))
+
+	articles, err := n.GetArticles()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The heat advisory expired, the tropical storm warning is only for the Virgin Islands, and
+	// the test message and the cancellation aren't alerts.
+	want := []struct {
+		title          string
+		location       string
+		municipalities []string
+		body           string
+		pinned         bool
+	}{
+		{"Hurricane Warning for San Juan, Carolina, Fajardo and 2 more", news.SanJuanName, []string{"San Juan", "Carolina", "Fajardo", "Culebra", "Canóvanas"}, "Municipalities: San Juan, Carolina, Fajardo, Culebra, Canóvanas.", true},
+		{"Flash Flood Watch", news.SanJuanName, nil, "Areas: Ponce and Vicinity, Southwest.", true},
+		{"Flood Advisory for Bayamón and Guaynabo", "Bayamón", []string{"Bayamón", "Guaynabo"}, "In effect until Sunday, September 1 at 4:45 PM AST.", false},
+	}
+
+	if len(articles) != len(want) {
+		t.Fatalf("got %d articles, want %d", len(articles), len(want))
+	}
+
+	for i, w := range want {
+		article := articles[i]
+		if article.Title != w.title || !strings.HasPrefix(w.title, article.MenuHeadline) || article.MenuHeadline == "" {
+			t.Errorf("article %d title = %q (menu %q), want %q", i, article.Title, article.MenuHeadline, w.title)
+		}
+
+		if article.Topic != news.NationalNews || article.Location == nil || article.Location.Name != w.location {
+			t.Errorf("article %d topic %d, location %+v", i, article.Topic, article.Location)
+		}
+
+		var names []string
+		for _, location := range article.Locations {
+			names = append(names, location.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(w.municipalities, ",") {
+			t.Errorf("article %d municipalities = %v, want %v", i, names, w.municipalities)
+		}
+
+		if article.Content == nil || !strings.Contains(*article.Content, w.body) {
+			t.Errorf("article %d body does not contain %q", i, w.body)
+		}
+
+		if article.Pinned != w.pinned {
+			t.Errorf("article %d pinned = %v, want %v", i, article.Pinned, w.pinned)
+		}
+	}
+}
+
+func TestAlertsLeadHeadlines(t *testing.T) {
+	now := time.Date(2024, time.September, 1, 14, 5, 0, 0, AtlanticStandardTime)
+	n, _ := newFixtureNWS(t, now)
+
+	// The feed article is newer than every alert, and in the same topic.
+	body := "Las lluvias regresan al área metropolitana."
+	feed := &newstest.Source{Articles: []news.Article{{Title: "Vuelven las lluvias", Content: &body, Topic: news.NationalNews, Published: now.Add(-time.Minute)}}}
+
+	result, err := generator.Generate(context.Background(), generator.Options{
+		CacheDir: t.TempDir(),
+		Signer:   signing.Unsigned{},
+		Source:   news.Combine(feed, n),
+		Clock:    generator.FixedClock(now),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := generator.DecodeFile(result.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"Hurricane Warning", "Flash Flood Watch", "Vuelven las lluvias", "Flood Advisory"}
+	if !reflect.DeepEqual(parsed.Headlines, want) {
+		t.Errorf("headlines = %q, want %q", parsed.Headlines, want)
+	}
+}
+
+func TestGetArticlesNoneActive(t *testing.T) {
+	n, _ := newFixtureNWS(t, time.Date(2024, time.September, 3, 0, 0, 0, 0, time.UTC))
+
+	articles, err := n.GetArticles()
+	if err != nil || len(articles) != 0 {
+		t.Errorf("got %d articles, %v, want none once every alert expired", len(articles), err)
+	}
+}
+
+func TestGetArticlesFailures(t *testing.T) {
+	for _, fault := range []newstest.Fault{newstest.FaultNotFound, newstest.FaultServerError, newstest.FaultMalformed} {
+		n, server := newFixtureNWS(t, time.Date(2024, time.September, 1, 14, 5, 0, 0, AtlanticStandardTime))
+		server.Fail(alertsPath, fault)
+
+		if _, err := n.GetArticles(); err == nil {
+			t.Errorf("fault %d: expected an error", fault)
+		}
+	}
+}
+
+func TestMunicipalities(t *testing.T) {
+	if len(Municipalities) != 78 {
+		t.Errorf("got %d municipalities, want 78", len(Municipalities))
+	}
+
+	for code, location := range Municipalities {
+		if !strings.HasPrefix(code, "072") || location.Name == "" {
+			t.Errorf("%s: %+v", code, location)
+		}
+
+		// Every municipality lies within Puerto Rico's bounding box, islands included.
+		if location.Latitude < 17.8 || location.Latitude > 18.6 || location.Longitude < -67.3 || location.Longitude > -65.2 {
+			t.Errorf("%s (%s) is outside Puerto Rico", code, location.Name)
+		}
+	}
+}
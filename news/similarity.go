@@ -0,0 +1,131 @@
+package news
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	// DefaultTitleThreshold is the normalized title similarity from which two articles are the same story.
+	DefaultTitleThreshold = 0.85
+	// DefaultBodyThreshold is the estimated share of body shingles two articles need in common to be
+	// the same story.
+	DefaultBodyThreshold = 0.6
+)
+
+const (
+	// shingleSize is the number of words in a body shingle.
+	shingleSize = 3
+	// fingerprintSize is the number of MinHash values in a fingerprint.
+	fingerprintSize = 32
+)
+
+// updatePrefix matches the labels outlets put in front of a re-titled story, such as "ACTUALIZADO:".
+var updatePrefix = regexp.MustCompile(`(?i)^\s*(actualizad[oa]|actualización|última hora|ultima hora|en vivo|en desarrollo|video|fotos|exclusiva|breaking|updated?|live)\s*[:|\-–—]\s*`)
+
+var accents = strings.NewReplacer("á", "a", "é", "e", "í", "i", "ó", "o", "ú", "u", "ü", "u", "ñ", "n")
+
+// NormalizeTitle reduces a title to lowercase words without accents, punctuation or update labels,
+// so re-titled versions of a story compare equal.
+func NormalizeTitle(title string) string {
+	for {
+		stripped := updatePrefix.ReplaceAllString(title, "")
+		if stripped == title {
+			break
+		}
+		title = stripped
+	}
+
+	return strings.Join(words(title), " ")
+}
+
+// words splits text into lowercase words without accents or punctuation.
+func words(text string) []string {
+	text = accents.Replace(strings.ToLower(text))
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// TitleSimilarity compares the words of two normalized titles, from 0 for nothing in common to 1
+// for the same title.
+func TitleSimilarity(a, b string) float64 {
+	aWords := strings.Fields(NormalizeTitle(a))
+	bWords := strings.Fields(NormalizeTitle(b))
+	if len(aWords) == 0 || len(bWords) == 0 {
+		return 0
+	}
+
+	return difflib.NewMatcher(aWords, bWords).Ratio()
+}
+
+// ContentHash identifies the words of a body, ignoring case, accents, punctuation and spacing, so
+// only changes to the text itself give a different hash. It is empty for an empty body.
+func ContentHash(text string) string {
+	bodyWords := words(text)
+	if len(bodyWords) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(bodyWords, " ")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Fingerprint is a MinHash signature of the word shingles of an article body. Fingerprints are
+// small enough to keep in the cache, and estimate how much two bodies have in common.
+type Fingerprint []uint32
+
+// BodyFingerprint returns the fingerprint of text, or nil if it is too short to have a shingle.
+func BodyFingerprint(text string) Fingerprint {
+	bodyWords := words(text)
+	if len(bodyWords) < shingleSize {
+		return nil
+	}
+
+	fingerprint := make(Fingerprint, fingerprintSize)
+	for i := range fingerprint {
+		fingerprint[i] = ^uint32(0)
+	}
+
+	for i := 0; i+shingleSize <= len(bodyWords); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(bodyWords[i:i+shingleSize], " ")))
+		shingle := h.Sum64()
+
+		for j := range fingerprint {
+			fingerprint[j] = min(fingerprint[j], uint32(mix(shingle+uint64(j)*0x9E3779B97F4A7C15)))
+		}
+	}
+
+	return fingerprint
+}
+
+// mix is the splitmix64 finalizer, which turns one hash into a family of independent ones.
+func mix(x uint64) uint64 {
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
+// Similarity estimates the share of shingles two bodies have in common. It is 0 when either body
+// had no fingerprint.
+func (f Fingerprint) Similarity(other Fingerprint) float64 {
+	if len(f) == 0 || len(f) != len(other) {
+		return 0
+	}
+
+	same := 0
+	for i := range f {
+		if f[i] == other[i] {
+			same++
+		}
+	}
+
+	return float64(same) / float64(len(f))
+}
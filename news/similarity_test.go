@@ -0,0 +1,71 @@
+package news_test
+
+import (
+	"WiiNewsPR/news"
+	"testing"
+)
+
+func TestNormalizeTitle(t *testing.T) {
+	tests := []struct {
+		title, want string
+	}{
+		{"ACTUALIZADO: Vuelven las lluvias al área metro", "vuelven las lluvias al area metro"},
+		{"Última hora - EN VIVO: ¡Cangrejeros ganan!", "cangrejeros ganan"},
+		{"Video: ¿Qué pasó en Caguas?", "que paso en caguas"},
+		// Only leading labels are stripped.
+		{"La AAA actualizado: nuevo horario", "la aaa actualizado nuevo horario"},
+	}
+
+	for _, tt := range tests {
+		if got := news.NormalizeTitle(tt.title); got != tt.want {
+			t.Errorf("NormalizeTitle(%q) = %q, want %q", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestTitleSimilarity(t *testing.T) {
+	previous := []string{"Gobernadora firma ley de presupuesto", "Cangrejeros ganan el primer juego de la final"}
+
+	tests := []struct {
+		title string
+		// want is whether the title is the same story as one of previous.
+		want bool
+	}{
+		{"GOBERNADORA FIRMA LEY DE PRESUPUESTO", true},
+		{"ACTUALIZADO: Gobernadora firma la ley de presupuesto", true},
+		{"Cangrejeros pierden ante los Vaqueros en Bayamón", false},
+		{"AAA anuncia interrupciones en Caguas", false},
+	}
+
+	for _, tt := range tests {
+		got := false
+		for _, title := range previous {
+			got = got || news.TitleSimilarity(tt.title, title) >= news.DefaultTitleThreshold
+		}
+		if got != tt.want {
+			t.Errorf("%q is a duplicate = %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestBodyFingerprint(t *testing.T) {
+	body := "El presupuesto de 13,100 millones entra en vigor el 1 de julio. La Legislatura lo aprobó la semana pasada tras semanas de debate."
+	edited := "El presupuesto de 13,100 millones entra en vigor el 1 de julio. La Legislatura lo aprobó la semana pasada tras semanas de debate intenso."
+	other := "Los Cangrejeros de Santurce ganaron el primer juego de la serie final ante los Vaqueros de Bayamón en el Coliseo."
+
+	a, b, c := news.BodyFingerprint(body), news.BodyFingerprint(edited), news.BodyFingerprint(other)
+
+	if got := a.Similarity(a); got != 1 {
+		t.Errorf("self similarity = %.2f", got)
+	}
+	if got := a.Similarity(b); got < news.DefaultBodyThreshold {
+		t.Errorf("edited body similarity = %.2f, want at least %.2f", got, news.DefaultBodyThreshold)
+	}
+	if got := a.Similarity(c); got > 0.2 {
+		t.Errorf("unrelated body similarity = %.2f", got)
+	}
+
+	if news.BodyFingerprint("muy corto") != nil || a.Similarity(nil) != 0 {
+		t.Error("short bodies should have no fingerprint")
+	}
+}
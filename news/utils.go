@@ -11,7 +11,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/pmezard/go-difflib/difflib"
 	"golang.org/x/image/draw"
 
 	"image"
@@ -44,18 +43,7 @@ func HttpGet(url string, userAgent ...string) ([]byte, error) {
 	return body, nil
 }
 
-func IsDuplicateArticle(previousArticles []string, currentArticle string) bool {
-	for _, previousArticle := range previousArticles {
-		diff := difflib.NewMatcher([]string{currentArticle}, []string{previousArticle})
-		if diff.QuickRatio() >= 0.85 {
-			return true
-		}
-	}
-
-	return false
-}
-
-func DownloadImage(imageURL string) ([]byte, error) {
+func DownloadImage(imageURL string, httpClient ...*http.Client) ([]byte, error) {
 	if imageURL == "" {
 		return nil, fmt.Errorf("empty image URL")
 	}
@@ -64,6 +52,10 @@ func DownloadImage(imageURL string) ([]byte, error) {
 		Timeout: 15 * time.Second,
 	}
 
+	if len(httpClient) > 0 && httpClient[0] != nil {
+		client = httpClient[0]
+	}
+
 	resp, err := client.Get(imageURL)
 	if err != nil {
 		return nil, err
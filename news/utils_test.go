@@ -0,0 +1,84 @@
+package news_test
+
+import (
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"bytes"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const imagePath = "/images/lluvia.jpg"
+
+func TestDownloadImage(t *testing.T) {
+	server := newstest.NewFeedServer(filepath.Join("endi", "testdata"))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 200 * time.Millisecond}
+
+	data, err := news.DownloadImage(server.URL+imagePath, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := os.ReadFile(filepath.Join("endi", "testdata", "images", "lluvia.jpg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Error("downloaded image differs from the fixture")
+	}
+
+	converted := news.ConvertImage(data)
+	config, err := jpeg.DecodeConfig(bytes.NewReader(converted))
+	if err != nil {
+		t.Fatalf("converted image is not a JPEG: %v", err)
+	}
+	if config.Width != 200 || config.Height != 200 {
+		t.Errorf("converted image is %dx%d, want 200x200", config.Width, config.Height)
+	}
+}
+
+func TestDownloadImageFaults(t *testing.T) {
+	tests := []struct {
+		name  string
+		fault newstest.Fault
+	}{
+		{"not found", newstest.FaultNotFound},
+		{"server error", newstest.FaultServerError},
+		{"slow", newstest.FaultSlow},
+		{"truncated", newstest.FaultTruncated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newstest.NewFeedServer(filepath.Join("endi", "testdata"))
+			defer server.Close()
+			server.Fail(imagePath, tt.fault)
+
+			data, err := news.DownloadImage(server.URL+imagePath, &http.Client{Timeout: 200 * time.Millisecond})
+			if err == nil {
+				t.Fatalf("expected an error, got %d bytes", len(data))
+			}
+		})
+	}
+
+	t.Run("malformed", func(t *testing.T) {
+		server := newstest.NewFeedServer(filepath.Join("endi", "testdata"))
+		defer server.Close()
+		server.Fail(imagePath, newstest.FaultMalformed)
+
+		data, err := news.DownloadImage(server.URL + imagePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if converted := news.ConvertImage(data); converted != nil {
+			t.Error("malformed image should not convert")
+		}
+	})
+}
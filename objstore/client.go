@@ -0,0 +1,213 @@
+// Package objstore is a minimal client for S3-compatible object storage, such as AWS S3, MinIO or
+// Cloudflare R2. It only implements the few calls the generator needs, so the module doesn't have
+// to depend on a full SDK.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when an object does not exist.
+	ErrNotFound = errors.New("object not found")
+	// ErrPreconditionFailed is returned when a conditional write finds the object changed.
+	ErrPreconditionFailed = errors.New("object precondition failed")
+)
+
+// Credentials sign requests. An empty access key sends them anonymously.
+type Credentials struct {
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// CredentialsFromEnv reads the standard AWS environment variables.
+func CredentialsFromEnv() Credentials {
+	return Credentials{
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+// Client talks to one bucket.
+type Client struct {
+	// Endpoint is the base URL of the service. Empty means AWS S3 in Region.
+	Endpoint string
+	Region   string
+	Bucket   string
+	// PathStyle puts the bucket in the path instead of the host name. MinIO and most
+	// self-hosted services need it. Buckets that can't be a host name label, such as ones with
+	// dots, always use it, as they wouldn't match the service's wildcard certificate.
+	PathStyle   bool
+	Credentials Credentials
+	HTTPClient  *http.Client
+}
+
+// Object is the contents of an object along with its entity tag.
+type Object struct {
+	Data []byte
+	ETag string
+}
+
+// Error is a response from the service that isn't a success.
+type Error struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("object storage returned %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// Get downloads an object.
+func (c *Client) Get(ctx context.Context, key string) (*Object, error) {
+	resp, err := c.do(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Object{Data: data, ETag: resp.Header.Get("ETag")}, nil
+}
+
+// PutOptions are the optional headers of an upload.
+type PutOptions struct {
+	ContentType string
+	// IfMatch only replaces the object if its entity tag is still this one.
+	IfMatch string
+	// IfNoneMatch set to "*" only creates the object if it doesn't exist yet.
+	IfNoneMatch string
+}
+
+// Put uploads an object, returning its new entity tag. A failed condition returns ErrPreconditionFailed.
+func (c *Client) Put(ctx context.Context, key string, data []byte, opts PutOptions) (string, error) {
+	header := http.Header{}
+	if opts.ContentType != "" {
+		header.Set("Content-Type", opts.ContentType)
+	}
+	if opts.IfMatch != "" {
+		header.Set("If-Match", opts.IfMatch)
+	}
+	if opts.IfNoneMatch != "" {
+		header.Set("If-None-Match", opts.IfNoneMatch)
+	}
+
+	resp, err := c.do(ctx, http.MethodPut, key, header, data)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	return resp.Header.Get("ETag"), nil
+}
+
+// Delete removes an object. Deleting a missing object is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	resp, err := c.do(ctx, http.MethodDelete, key, nil, nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// objectURL is the URL of key, in path or virtual-hosted style.
+func (c *Client) objectURL(key string) (*url.URL, error) {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.Region)
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid object storage endpoint: %w", err)
+	}
+
+	if c.PathStyle || !virtualHostable(c.Bucket) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + c.Bucket + "/" + key
+	} else {
+		u.Host = c.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+
+	return u, nil
+}
+
+// virtualHostable reports whether bucket can be the first label of a host name covered by a
+// wildcard certificate: 3 to 63 lowercase letters, digits and hyphens, without dots.
+func virtualHostable(bucket string) bool {
+	if len(bucket) < 3 || len(bucket) > 63 || bucket[0] == '-' || bucket[len(bucket)-1] == '-' {
+		return false
+	}
+
+	for _, r := range bucket {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// do sends a signed request and turns error responses into errors. The caller closes the body.
+func (c *Client) do(ctx context.Context, method, key string, header http.Header, body []byte) (*http.Response, error) {
+	u, err := c.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if c.Credentials.AccessKey != "" {
+		signRequest(req, hashHex(body), c.Credentials, c.Region, time.Now())
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	case http.StatusPreconditionFailed, http.StatusConflict:
+		// S3 answers 409 when a concurrent conditional write to the same key is in flight.
+		return nil, ErrPreconditionFailed
+	}
+
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return nil, &Error{StatusCode: resp.StatusCode, Body: string(data)}
+}
@@ -0,0 +1,123 @@
+package objstore_test
+
+import (
+	"WiiNewsPR/objstore"
+	"WiiNewsPR/objstore/objstoretest"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	client := server.Client()
+
+	if _, err := client.Get(ctx, "v2/1/049/news.bin.00"); !errors.Is(err, objstore.ErrNotFound) {
+		t.Fatalf("Get missing object: err = %v, want ErrNotFound", err)
+	}
+
+	etag, err := client.Put(ctx, "v2/1/049/news.bin.00", []byte("news"), objstore.PutOptions{ContentType: "application/octet-stream"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := client.Get(ctx, "v2/1/049/news.bin.00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(obj.Data) != "news" || obj.ETag != etag {
+		t.Errorf("Get = %q %s, want %q %s", obj.Data, obj.ETag, "news", etag)
+	}
+
+	if err = client.Delete(ctx, "v2/1/049/news.bin.00"); err != nil {
+		t.Fatal(err)
+	}
+	if err = client.Delete(ctx, "v2/1/049/news.bin.00"); err != nil {
+		t.Errorf("deleting a missing object: %v", err)
+	}
+}
+
+func TestClientRejectedCredentials(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	client := server.Client()
+	client.Credentials.AccessKey = "someone-else"
+
+	var storageErr *objstore.Error
+	if _, err := client.Get(context.Background(), "cache.json"); !errors.As(err, &storageErr) || storageErr.StatusCode != 403 {
+		t.Errorf("err = %v, want a 403", err)
+	}
+}
+
+func TestClientConditionalPut(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	ctx := context.Background()
+	client := server.Client()
+
+	first, err := client.Put(ctx, "cache.json", []byte("1"), objstore.PutOptions{IfNoneMatch: "*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = client.Put(ctx, "cache.json", []byte("2"), objstore.PutOptions{IfNoneMatch: "*"}); !errors.Is(err, objstore.ErrPreconditionFailed) {
+		t.Errorf("creating an existing object: err = %v, want ErrPreconditionFailed", err)
+	}
+
+	second, err := client.Put(ctx, "cache.json", []byte("2"), objstore.PutOptions{IfMatch: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first entity tag is stale now, so a writer that read before the second write loses.
+	if _, err = client.Put(ctx, "cache.json", []byte("3"), objstore.PutOptions{IfMatch: first}); !errors.Is(err, objstore.ErrPreconditionFailed) {
+		t.Errorf("replacing with a stale entity tag: err = %v, want ErrPreconditionFailed", err)
+	}
+
+	if data, _ := server.Object("cache.json"); string(data) != "2" || second == first {
+		t.Errorf("object = %q", data)
+	}
+}
+
+// roundTripFunc answers requests without a network.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientBucketAddressing(t *testing.T) {
+	for _, test := range []struct {
+		bucket string
+		want   string
+	}{
+		{"news", "https://news.s3.us-east-1.amazonaws.com/v2/1/049/news.bin.14"},
+		{"wii.rauln.com", "https://s3.us-east-1.amazonaws.com/wii.rauln.com/v2/1/049/news.bin.14"},
+		{"Wii_News", "https://s3.us-east-1.amazonaws.com/Wii_News/v2/1/049/news.bin.14"},
+	} {
+		var got string
+		client := &objstore.Client{
+			Region: "us-east-1",
+			Bucket: test.bucket,
+			HTTPClient: &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				got = req.URL.String()
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+			})},
+		}
+
+		if _, err := client.Get(context.Background(), "v2/1/049/news.bin.14"); !errors.Is(err, objstore.ErrNotFound) {
+			t.Fatalf("%s: err = %v", test.bucket, err)
+		}
+		if got != test.want {
+			t.Errorf("%s: URL = %s, want %s", test.bucket, got, test.want)
+		}
+	}
+}
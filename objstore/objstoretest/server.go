@@ -0,0 +1,148 @@
+// Package objstoretest provides an in-memory stand-in for S3-compatible object storage, in the
+// style of a local MinIO server, so object storage backends can be tested offline.
+package objstoretest
+
+import (
+	"WiiNewsPR/objstore"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// AccessKey and SecretKey are the credentials the server accepts.
+	AccessKey = "wiinewspr-test"
+	SecretKey = "wiinewspr-test-secret"
+	// Region is the region clients are configured for.
+	Region = "us-east-1"
+)
+
+type object struct {
+	data []byte
+	etag string
+}
+
+// Server serves a single bucket with path-style requests. Uploads honour If-Match and
+// If-None-Match: * like S3 conditional writes.
+type Server struct {
+	*httptest.Server
+
+	bucket string
+
+	mu      sync.Mutex
+	objects map[string]object
+}
+
+// NewServer starts a server for bucket. Close it when done.
+func NewServer(bucket string) *Server {
+	s := &Server{
+		bucket:  bucket,
+		objects: map[string]object{},
+	}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+// Client returns a client for the bucket with valid credentials.
+func (s *Server) Client() *objstore.Client {
+	return &objstore.Client{
+		Endpoint:    s.URL,
+		Region:      Region,
+		Bucket:      s.bucket,
+		PathStyle:   true,
+		Credentials: objstore.Credentials{AccessKey: AccessKey, SecretKey: SecretKey},
+		HTTPClient:  s.Server.Client(),
+	}
+}
+
+// Object returns the contents of key, if it exists.
+func (s *Server) Object(key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[key]
+	return obj.data, ok
+}
+
+// SetObject stores data under key, bypassing the HTTP interface.
+func (s *Server) SetObject(key string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = newObject(data)
+}
+
+// Keys lists every object key in order.
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.objects))
+	for key := range s.objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func newObject(data []byte) object {
+	sum := md5.Sum(data)
+	return object{data: data, etag: `"` + hex.EncodeToString(sum[:]) + `"`}
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="+AccessKey+"/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if bucket != s.bucket {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		obj, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("ETag", obj.etag)
+		w.Write(obj.data)
+	case http.MethodPut:
+		current, exists := s.objects[key]
+		if match := r.Header.Get("If-Match"); match != "" && (!exists || match != current.etag) {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+		if r.Header.Get("If-None-Match") == "*" && exists {
+			http.Error(w, "PreconditionFailed", http.StatusPreconditionFailed)
+			return
+		}
+
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		obj := newObject(data)
+		s.objects[key] = obj
+		w.Header().Set("ETag", obj.etag)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
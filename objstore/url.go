@@ -0,0 +1,54 @@
+package objstore
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Scheme is the URL scheme of object storage locations.
+const Scheme = "s3"
+
+// DefaultRegion is used when AWS_REGION is not set.
+const DefaultRegion = "us-east-1"
+
+// IsURL reports whether location names object storage rather than a local path.
+func IsURL(location string) bool {
+	return strings.HasPrefix(location, Scheme+"://")
+}
+
+// FromURL returns a client for an s3://bucket/prefix location, along with the key prefix. The
+// endpoint comes from WIINEWSPR_S3_ENDPOINT, which also switches to path-style requests, and the
+// region and credentials from the standard AWS variables.
+func FromURL(location string) (*Client, string, error) {
+	u, err := url.Parse(location)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if u.Scheme != Scheme || u.Host == "" {
+		return nil, "", fmt.Errorf("invalid object storage location %q, want %s://bucket/prefix", location, Scheme)
+	}
+
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = DefaultRegion
+	}
+
+	endpoint := os.Getenv("WIINEWSPR_S3_ENDPOINT")
+	client := &Client{
+		Endpoint:    endpoint,
+		Region:      region,
+		Bucket:      u.Host,
+		PathStyle:   endpoint != "",
+		Credentials: CredentialsFromEnv(),
+	}
+
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return client, prefix, nil
+}
@@ -0,0 +1,88 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Sunday.
+	base := time.Date(2024, time.September, 1, 18, 45, 10, 0, time.UTC)
+
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{Hourly, base, time.Date(2024, time.September, 1, 19, 30, 0, 0, time.UTC)},
+		{Hourly, base.Add(-20 * time.Minute), time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)},
+		// A run due at exactly the given time is the next one after it.
+		{Hourly, time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC), time.Date(2024, time.September, 1, 19, 30, 0, 0, time.UTC)},
+		{"30 * * * *", time.Date(2024, time.December, 31, 23, 40, 0, 0, time.UTC), time.Date(2025, time.January, 1, 0, 30, 0, 0, time.UTC)},
+		{"*/15 * * * *", base, time.Date(2024, time.September, 1, 19, 0, 0, 0, time.UTC)},
+		{"0 6-8,20 * * *", base, time.Date(2024, time.September, 1, 20, 0, 0, 0, time.UTC)},
+		{"5/20 * * * *", base, time.Date(2024, time.September, 1, 19, 5, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", base, time.Date(2024, time.September, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", base, time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Restricting both day fields matches either of them.
+		{"0 0 15 * 3", base, time.Date(2024, time.September, 4, 0, 0, 0, 0, time.UTC)},
+		{"30 * * * ?", base, time.Date(2024, time.September, 1, 19, 30, 0, 0, time.UTC)},
+		{"0 0 30 2 *", base, time.Time{}},
+	}
+
+	for _, test := range tests {
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.expr, err)
+		}
+
+		if got := s.Next(test.after); !got.Equal(test.want) {
+			t.Errorf("%q after %s = %s, want %s", test.expr, test.after, got, test.want)
+		}
+	}
+}
+
+func TestNextInLocation(t *testing.T) {
+	location := time.FixedZone("AST", -4*60*60)
+	s, err := Parse("30 6 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := s.Next(time.Date(2024, time.September, 1, 12, 0, 0, 0, location))
+	if want := time.Date(2024, time.September, 2, 6, 30, 0, 0, location); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+
+	// The hours of a zone with a half-hour offset start halfway through the UTC ones.
+	kolkata := time.FixedZone("IST", 5*60*60+30*60)
+	if s, err = Parse("0 11 * * *"); err != nil {
+		t.Fatal(err)
+	}
+
+	got = s.Next(time.Date(2024, time.September, 1, 10, 15, 0, 0, kolkata))
+	if want := time.Date(2024, time.September, 1, 11, 0, 0, 0, kolkata); !got.Equal(want) {
+		t.Errorf("next = %s, want %s", got, want)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"30 * * *",
+		"30 * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 7",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"a * * * *",
+		"1,,2 * * * *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded", expr)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+// Package server serves generated news files over HTTP the way the official endpoint does, so
+// Dolphin or a patched Wii can be pointed at a local machine.
+package server
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/sink"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// filePrefix starts the name of every hourly file.
+const filePrefix = "news.bin."
+
+// Server hosts /v2/{lang}/{country}/news.bin.{hour} from a directory of generated files.
+type Server struct {
+	// Dir holds the generated files, laid out as v2/{lang}/{country}/news.bin.{hour}.
+	Dir string
+	// Options configures generation. Its clock and time zones also decide the current hours, and
+	// its output is always Dir.
+	Options generator.Options
+	// OnDemand generates the current hour's file when it is requested and missing or stale.
+	OnDemand bool
+
+	mux *http.ServeMux
+	// mu serialises generation, so simultaneous requests only build the file once.
+	mu sync.Mutex
+}
+
+// New returns a server for the files in dir.
+func New(dir string, opts generator.Options, onDemand bool) *Server {
+	if opts.LanguageCode == 0 {
+		opts.LanguageCode = generator.DefaultLanguageCode
+	}
+
+	if opts.CountryCode == 0 {
+		opts.CountryCode = generator.DefaultCountryCode
+	}
+
+	if opts.Clock == nil {
+		opts.Clock = generator.SystemClock{}
+	}
+
+	opts.Output = sink.NewDirSink(dir)
+
+	s := &Server{
+		Dir:      dir,
+		Options:  opts,
+		OnDemand: onDemand,
+		mux:      http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /v2/{lang}/{country}/{file}", s.serveFile)
+	s.mux.HandleFunc("GET /{$}", s.serveStatus)
+	return s
+}
+
+// statusRecorder remembers the status code written, for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// ServeHTTP logs every request, so it is easy to see what the console asks for.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+
+	s.mux.ServeHTTP(recorder, r)
+
+	log.Printf("%s %s %s %d (%s) %q\n", r.RemoteAddr, r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond), r.UserAgent())
+}
+
+// path is where the file for hour is kept, relative to Dir.
+func (s *Server) path(hour int) string {
+	return generator.Result{LanguageCode: s.Options.LanguageCode, CountryCode: s.Options.CountryCode, Hour: hour}.Path()
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, r *http.Request) {
+	lang, langErr := strconv.Atoi(r.PathValue("lang"))
+	country, countryErr := strconv.Atoi(r.PathValue("country"))
+	hour, hourErr := strconv.Atoi(strings.TrimPrefix(r.PathValue("file"), filePrefix))
+	if langErr != nil || countryErr != nil || hourErr != nil || !strings.HasPrefix(r.PathValue("file"), filePrefix) || hour < 0 || hour > 23 {
+		http.NotFound(w, r)
+		return
+	}
+
+	if lang != int(s.Options.LanguageCode) || country != int(s.Options.CountryCode) {
+		http.NotFound(w, r)
+		return
+	}
+
+	data, err := s.file(r.Context(), hour)
+	if errors.Is(err, os.ErrNotExist) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to serve hour %02d: %v\n", hour, err)
+		http.Error(w, "failed to generate news file", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Write(data)
+}
+
+// file returns the file for hour if it was generated within the last day. When OnDemand is set, a
+// missing or stale file for the current hour is generated first.
+func (s *Server) file(ctx context.Context, hour int) ([]byte, error) {
+	now := s.Options.LocalNow()
+	hourStart := nextTick(now, time.Hour).Add(-time.Hour)
+
+	data, updated, err := s.read(hour)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if slices.Contains(s.Options.CurrentHours(), hour) {
+		if err == nil && !updated.Before(hourStart) {
+			return data, nil
+		}
+
+		if !s.OnDemand {
+			return nil, os.ErrNotExist
+		}
+
+		return s.generateIfStale(ctx)
+	}
+
+	// Other hours are only served from disk, and yesterday's file for an hour is not today's.
+	if err != nil || !updated.After(now.Add(-24*time.Hour)) {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+// read loads the file for hour, along with the time it was generated for.
+func (s *Server) read(hour int) ([]byte, time.Time, error) {
+	data, err := os.ReadFile(filepath.Join(s.Dir, s.path(hour)))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	parsed, err := generator.DecodeFile(data)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%s: %w", s.path(hour), err)
+	}
+
+	return data, generator.ParseTimestamp(parsed.Header.UpdatedTimestamp), nil
+}
+
+// generateIfStale generates the current hour's file unless another request did while this one waited.
+func (s *Server) generateIfStale(ctx context.Context) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Options.LocalNow()
+	if data, updated, err := s.read(now.Hour()); err == nil && !updated.Before(nextTick(now, time.Hour).Add(-time.Hour)) {
+		return data, nil
+	}
+
+	result, err := s.generate(ctx)
+	return result.Data, err
+}
+
+// Generate builds the file for the current hour and writes it to Dir.
+func (s *Server) Generate(ctx context.Context) (generator.Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.generate(ctx)
+}
+
+func (s *Server) generate(ctx context.Context) (generator.Result, error) {
+	result, err := generator.Generate(ctx, s.Options)
+	if err != nil {
+		return generator.Result{}, err
+	}
+
+	for _, change := range result.Filtered {
+		log.Printf("Filter: %s\n", change)
+	}
+	log.Printf("Generated %s with %d articles\n", result.Location, result.NumberOfArticles)
+	return result, nil
+}
+
+// Schedule generates the current hour's file straight away and then at every multiple of
+// interval since local midnight by the clock, until ctx is done. Failures are logged and retried
+// at the next tick.
+func (s *Server) Schedule(ctx context.Context, interval time.Duration) {
+	for {
+		if _, err := s.Generate(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Scheduled generation failed: %v\n", err)
+		}
+
+		now := s.Options.LocalNow()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(nextTick(now, interval).Sub(now)):
+		}
+	}
+}
+
+// nextTick returns the first multiple of interval since local midnight after t. Counting from
+// midnight rather than Truncate keeps hours aligned in zones with a half-hour offset.
+func nextTick(t time.Time, interval time.Duration) time.Time {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return midnight.Add(t.Sub(midnight).Truncate(interval) + interval)
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/news"
+	"WiiNewsPR/news/newstest"
+	"WiiNewsPR/signing"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testTime = time.Date(2024, time.September, 1, 18, 30, 0, 0, time.UTC)
+
+func newTestServer(t *testing.T, source *newstest.Source, at time.Time, onDemand bool) (*Server, *httptest.Server) {
+	t.Helper()
+
+	s := New(t.TempDir(), generator.Options{
+		CacheDir: t.TempDir(),
+		Signer:   signing.Unsigned{},
+		Source:   source,
+		Clock:    generator.FixedClock(at),
+	}, onDemand)
+
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return s, ts
+}
+
+func get(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func testSource() *newstest.Source {
+	body := "Las lluvias regresan al área metropolitana."
+	return &newstest.Source{Articles: []news.Article{{Title: "Vuelven las lluvias al área metro", Content: &body, Topic: news.NationalNews}}}
+}
+
+func TestServeOnDemand(t *testing.T) {
+	source := testSource()
+	s, ts := newTestServer(t, source, testTime, true)
+
+	status, body := get(t, ts.URL+"/v2/1/049/news.bin.18")
+	if status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+
+	parsed, err := generator.DecodeFile(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(parsed.Articles) != 1 || parsed.Articles[0].Title != "Vuelven las lluvias al área metro" {
+		t.Errorf("articles = %+v", parsed.Articles)
+	}
+
+	if _, err = os.Stat(filepath.Join(s.Dir, "v2/1/049/news.bin.18")); err != nil {
+		t.Errorf("file was not kept: %v", err)
+	}
+
+	// The file is current now, so the source isn't asked again.
+	source.Err = errors.New("source must not be called")
+	if status, again := get(t, ts.URL+"/v2/1/049/news.bin.18"); status != http.StatusOK || string(again) != string(body) {
+		t.Errorf("second request: status %d", status)
+	}
+}
+
+func TestServeNotFound(t *testing.T) {
+	_, ts := newTestServer(t, testSource(), testTime, true)
+
+	for _, path := range []string{
+		"/v2/1/050/news.bin.18",
+		"/v2/2/049/news.bin.18",
+		"/v2/1/049/news.bin.24",
+		"/v2/1/049/other.bin.18",
+		// Other hours are never generated on demand.
+		"/v2/1/049/news.bin.17",
+	} {
+		if status, _ := get(t, ts.URL+path); status != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, status)
+		}
+	}
+
+	_, offline := newTestServer(t, testSource(), testTime, false)
+	if status, _ := get(t, offline.URL+"/v2/1/049/news.bin.18"); status != http.StatusNotFound {
+		t.Errorf("without on-demand generation: status = %d, want 404", status)
+	}
+}
+
+func TestServeRegeneratesStaleFile(t *testing.T) {
+	source := testSource()
+	s, ts := newTestServer(t, source, testTime, true)
+
+	// Yesterday's file for this hour.
+	yesterday := s.Options
+	yesterday.Clock = generator.FixedClock(testTime.Add(-24 * time.Hour))
+	if _, err := generator.Generate(context.Background(), yesterday); err != nil {
+		t.Fatal(err)
+	}
+
+	if status, _ := get(t, ts.URL+"/v2/1/049/news.bin.18"); status != http.StatusOK {
+		t.Fatalf("status = %d", status)
+	}
+
+	_, updated, err := s.read(18)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Equal(testTime.Truncate(time.Minute)) {
+		t.Errorf("file is for %v, want it regenerated for %v", updated, testTime)
+	}
+}
+
+func TestServeStatus(t *testing.T) {
+	s, ts := newTestServer(t, testSource(), testTime, false)
+
+	if _, body := get(t, ts.URL+"/"); !strings.Contains(string(body), "not been generated yet") {
+		t.Errorf("status page without a file:\n%s", body)
+	}
+
+	if _, err := s.Generate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	_, body := get(t, ts.URL+"/")
+	for _, want := range []string{"v2/1/049/news.bin.18", "Vuelven las lluvias al área metro", "National News"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("status page is missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestNextTick(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		at       time.Time
+		interval time.Duration
+		want     time.Time
+	}{
+		{testTime, time.Hour, time.Date(2024, time.September, 1, 19, 0, 0, 0, time.UTC)},
+		{testTime, 15 * time.Minute, time.Date(2024, time.September, 1, 18, 45, 0, 0, time.UTC)},
+		// 18:30 UTC is midnight in India, an hour boundary that Truncate would miss.
+		{testTime.In(kolkata).Add(-time.Minute), time.Hour, time.Date(2024, time.September, 2, 0, 0, 0, 0, kolkata)},
+		{testTime.In(kolkata).Add(20 * time.Minute), time.Hour, time.Date(2024, time.September, 2, 1, 0, 0, 0, kolkata)},
+	} {
+		if got := nextTick(test.at, test.interval); !got.Equal(test.want) {
+			t.Errorf("nextTick(%s, %s) = %s, want %s", test.at, test.interval, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"WiiNewsPR/generator"
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+var statusTemplate = template.Must(template.New("status").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>WiiNewsPR</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border-bottom: 1px solid #ddd; padding: 0.3em 0.8em; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>WiiNewsPR</h1>
+<p>Serving <code>/{{.Path}}</code> for hour {{printf "%02d" .Hour}}.</p>
+{{- if .Error}}
+<p>{{.Error}}</p>
+{{- else}}
+<p>Generated for {{.Updated.Format "2006-01-02 15:04 MST"}}, {{len .File.Articles}} articles, {{len .File.Images}} images, {{.Size}} bytes.</p>
+{{- if .File.Message}}
+<p><strong>Message:</strong> {{.File.Message}}</p>
+{{- end}}
+<table>
+<tr><th>ID</th><th>Topic</th><th>Title</th><th>Published</th><th>Picture</th></tr>
+{{- range .Articles}}
+<tr><td>{{.ID}}</td><td>{{.Topic}}</td><td>{{.Title}}</td><td>{{.Published.Format "15:04"}}{{if .Updated}} (updated {{.UpdatedAt.Format "15:04"}}){{end}}</td><td>{{if .HasPicture}}yes{{end}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+// statusArticle is a row of the status page.
+type statusArticle struct {
+	generator.ParsedArticle
+	Published  time.Time
+	UpdatedAt  time.Time
+	Updated    bool
+	HasPicture bool
+}
+
+// serveStatus lists the articles in the current hour's file. It never generates the file.
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	hour := s.Options.LocalNow().Hour()
+	page := struct {
+		Path     string
+		Hour     int
+		Error    string
+		Updated  time.Time
+		Size     int
+		File     *generator.ParsedFile
+		Articles []statusArticle
+	}{Path: s.path(hour), Hour: hour}
+
+	data, updated, err := s.read(hour)
+	if err == nil {
+		page.File, err = generator.DecodeFile(data)
+	}
+
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		page.Error = "The file for this hour has not been generated yet."
+	case err != nil:
+		page.Error = "The file for this hour could not be read: " + err.Error()
+	default:
+		page.Updated = updated.In(s.Options.LocalNow().Location())
+		page.Size = len(data)
+		for _, article := range page.File.Articles {
+			page.Articles = append(page.Articles, statusArticle{
+				ParsedArticle: article,
+				Published:     generator.ParseTimestamp(article.PublishedTime).In(page.Updated.Location()),
+				UpdatedAt:     generator.ParseTimestamp(article.UpdatedTime).In(page.Updated.Location()),
+				Updated:       article.UpdatedTime != article.PublishedTime,
+				HasPicture:    article.PictureIndex != generator.NoPicture,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err = statusTemplate.Execute(w, page); err != nil {
+		log.Printf("Failed to render status page: %v\n", err)
+	}
+}
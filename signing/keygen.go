@@ -0,0 +1,49 @@
+package signing
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+)
+
+// KeySize is the RSA modulus size the News Channel verifies against.
+const KeySize = 2048
+
+// GenerateKey creates a new RSA key pair compatible with the channel.
+func GenerateKey() (*rsa.PrivateKey, error) {
+	key, err := rsa.GenerateKey(rand.Reader, KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	return key, nil
+}
+
+// EncodePrivateKey encodes the key as a PKCS#1 PEM block.
+func EncodePrivateKey(key *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	})
+}
+
+// EncodePublicKey encodes the key as a PKIX PEM block.
+func EncodePublicKey(key *rsa.PublicKey) ([]byte, error) {
+	data, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: data,
+	}), nil
+}
+
+// ChannelPublicKey returns the raw big-endian modulus, padded to the key size. This is what gets
+// patched over the public key embedded in the channel. The exponent is always 65537.
+func ChannelPublicKey(key *rsa.PublicKey) []byte {
+	return key.N.FillBytes(make([]byte, key.Size()))
+}
@@ -0,0 +1,52 @@
+package signing
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"fmt"
+)
+
+// PEMSigner signs with an RSA private key held in memory.
+type PEMSigner struct {
+	key *rsa.PrivateKey
+}
+
+// NewPEMSigner loads the private key at path. It must be a KeySize-bit key, as the file only has
+// room for a signature of that size.
+func NewPEMSigner(path string) (*PEMSigner, error) {
+	key, err := LoadPrivateKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key.Size() != SignatureSize {
+		return nil, fmt.Errorf("%s is a %d-bit key, want %d bits", path, key.N.BitLen(), KeySize)
+	}
+
+	return NewKeySigner(key), nil
+}
+
+// NewKeySigner wraps an already parsed private key. Signing fails unless it is a KeySize-bit key.
+func NewKeySigner(key *rsa.PrivateKey) *PEMSigner {
+	return &PEMSigner{key: key}
+}
+
+func (s *PEMSigner) SignDigest(_ context.Context, digest []byte) ([]byte, error) {
+	if len(digest) != sha1.Size {
+		return nil, fmt.Errorf("expected a %d byte SHA-1 digest, got %d bytes", sha1.Size, len(digest))
+	}
+
+	if s.key.Size() != SignatureSize {
+		return nil, fmt.Errorf("a %d-bit key makes %d-byte signatures, want %d", s.key.N.BitLen(), s.key.Size(), SignatureSize)
+	}
+
+	// PKCS#1 v1.5 signatures are deterministic, so no randomness is passed in.
+	return rsa.SignPKCS1v15(nil, s.key, crypto.SHA1, digest)
+}
+
+// PublicKey returns the public half of the signing key.
+func (s *PEMSigner) PublicKey() *rsa.PublicKey {
+	return &s.key.PublicKey
+}
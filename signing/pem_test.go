@@ -0,0 +1,33 @@
+package signing
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPEMSignerKeySize(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "Private.pem")
+	if err = os.WriteFile(path, EncodePrivateKey(key), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Its 128-byte signatures would shift everything after the signature slot.
+	if _, err = NewPEMSigner(path); err == nil {
+		t.Error("a 1024-bit key was accepted")
+	}
+
+	digest := sha1.Sum([]byte("news.bin contents"))
+	if _, err = NewKeySigner(key).SignDigest(context.Background(), digest[:]); err == nil {
+		t.Error("a 1024-bit key signed")
+	}
+}
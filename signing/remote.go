@@ -0,0 +1,100 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SignRequest is the body sent to a signing service. Only the digest leaves the generator.
+type SignRequest struct {
+	Algorithm string `json:"algorithm"`
+	Digest    []byte `json:"digest"`
+}
+
+// SignResponse is the body returned by a signing service.
+type SignResponse struct {
+	Signature []byte `json:"signature"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AlgorithmSHA1 is the only algorithm the News Channel understands.
+const AlgorithmSHA1 = "rsa-pkcs1v15-sha1"
+
+// RemoteSigner asks an HTTP signing service to sign digests, so the private key never
+// has to live next to the generator.
+type RemoteSigner struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+// NewRemoteSigner creates a signer for the service at url. The token is sent as a bearer token if set.
+func NewRemoteSigner(url, token string) *RemoteSigner {
+	return &RemoteSigner{
+		URL:   url,
+		Token: token,
+		Client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+	}
+}
+
+func (s *RemoteSigner) SignDigest(ctx context.Context, digest []byte) ([]byte, error) {
+	body, err := json.Marshal(SignRequest{
+		Algorithm: AlgorithmSHA1,
+		Digest:    digest,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create signing request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("signing service request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing service response: %w", err)
+	}
+
+	var signResponse SignResponse
+	if err = json.Unmarshal(data, &signResponse); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse signing service response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if signResponse.Error != "" {
+			return nil, fmt.Errorf("signing service returned status %d: %s", resp.StatusCode, signResponse.Error)
+		}
+		return nil, fmt.Errorf("signing service returned status %d", resp.StatusCode)
+	}
+
+	// Everything after the signature is located by its size, so anything else would shift the file.
+	if len(signResponse.Signature) != SignatureSize {
+		return nil, fmt.Errorf("signing service returned a %d-byte signature, want %d", len(signResponse.Signature), SignatureSize)
+	}
+
+	return signResponse.Signature, nil
+}
@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRemoteSignerMatchesLocal(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local := NewKeySigner(key)
+	server := httptest.NewServer(NewServer(local, "secret"))
+	defer server.Close()
+
+	contents := []byte("news.bin contents")
+	want, err := SignFile(context.Background(), local, contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := SignFile(context.Background(), NewRemoteSigner(server.URL, "secret"), contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("remote signature differs from local signature")
+	}
+
+	if err = VerifyFile(got, &key.PublicKey); err != nil {
+		t.Errorf("VerifyFile: %v", err)
+	}
+
+	if _, err = SignFile(context.Background(), NewRemoteSigner(server.URL, "wrong"), contents); err == nil {
+		t.Error("expected an error for a wrong token")
+	}
+}
+
+func TestRemoteSignerSignatureSize(t *testing.T) {
+	for _, size := range []int{0, SignatureSize - 1, SignatureSize + 1} {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(SignResponse{Signature: make([]byte, size)})
+		}))
+
+		_, err := NewRemoteSigner(server.URL, "").SignDigest(context.Background(), make([]byte, 20))
+		if err == nil || !strings.Contains(err.Error(), "signature") {
+			t.Errorf("%d-byte signature: err = %v", size, err)
+		}
+		server.Close()
+	}
+}
@@ -0,0 +1,75 @@
+package signing
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+)
+
+// maxRequestSize bounds the request body. A request only carries a digest.
+const maxRequestSize = 4096
+
+// Server is a minimal stand-in for a signing service. It wraps any Signer, usually a
+// PEMSigner, and speaks the protocol RemoteSigner expects.
+type Server struct {
+	Signer Signer
+	// Token, when set, must be presented as a bearer token.
+	Token string
+}
+
+// NewServer creates a signing server backed by signer.
+func NewServer(signer Signer, token string) *Server {
+	return &Server{
+		Signer: signer,
+		Token:  token,
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeSignResponse(w, http.StatusMethodNotAllowed, SignResponse{Error: "only POST is supported"})
+		return
+	}
+
+	if s.Token != "" {
+		expected := []byte("Bearer " + s.Token)
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			writeSignResponse(w, http.StatusUnauthorized, SignResponse{Error: "invalid token"})
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestSize))
+	if err != nil {
+		writeSignResponse(w, http.StatusBadRequest, SignResponse{Error: "failed to read request"})
+		return
+	}
+
+	var request SignRequest
+	if err = json.Unmarshal(data, &request); err != nil {
+		writeSignResponse(w, http.StatusBadRequest, SignResponse{Error: "malformed request"})
+		return
+	}
+
+	if request.Algorithm != AlgorithmSHA1 {
+		writeSignResponse(w, http.StatusBadRequest, SignResponse{Error: "unsupported algorithm " + request.Algorithm})
+		return
+	}
+
+	signature, err := s.Signer.SignDigest(r.Context(), request.Digest)
+	if err != nil {
+		writeSignResponse(w, http.StatusBadRequest, SignResponse{Error: err.Error()})
+		return
+	}
+
+	log.Printf("Signed digest %x for %s\n", request.Digest, r.RemoteAddr)
+	writeSignResponse(w, http.StatusOK, SignResponse{Signature: signature})
+}
+
+func writeSignResponse(w http.ResponseWriter, status int, response SignResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(response)
+}
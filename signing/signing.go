@@ -0,0 +1,64 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha1"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// signaturePadding is the amount of zero bytes written before the signature.
+const signaturePadding = 64
+
+// DefaultKeyPath is used when neither the -k flag nor WIINEWSPR_KEY are set.
+const DefaultKeyPath = "Private.pem"
+
+// Signer produces the RSA PKCS#1 v1.5 signature the News Channel expects over a SHA-1 digest.
+type Signer interface {
+	SignDigest(ctx context.Context, digest []byte) ([]byte, error)
+}
+
+// KeyPathFromEnv returns the private key path from WIINEWSPR_KEY, falling back to DefaultKeyPath.
+func KeyPathFromEnv() string {
+	if path := os.Getenv("WIINEWSPR_KEY"); path != "" {
+		return path
+	}
+
+	return DefaultKeyPath
+}
+
+// SignFile prepends the padding and the signature of the contents produced by signer.
+func SignFile(ctx context.Context, signer Signer, contents []byte) ([]byte, error) {
+	hash := sha1.Sum(contents)
+	signature, err := signer.SignDigest(ctx, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign file: %w", err)
+	}
+
+	buffer := new(bytes.Buffer)
+	buffer.Write(make([]byte, signaturePadding))
+	buffer.Write(signature)
+	buffer.Write(contents)
+
+	return buffer.Bytes(), nil
+}
+
+// VerifyFile checks the signature of a signed news file against the given public key.
+func VerifyFile(data []byte, key *rsa.PublicKey) error {
+	signatureEnd := signaturePadding + key.Size()
+	if len(data) < signatureEnd {
+		return errors.New("file is too small to contain a signature")
+	}
+
+	hash := sha1.Sum(data[signatureEnd:])
+	err := rsa.VerifyPKCS1v15(key, crypto.SHA1, hash[:], data[signaturePadding:signatureEnd])
+	if err != nil {
+		return fmt.Errorf("signature mismatch: %w", err)
+	}
+
+	return nil
+}
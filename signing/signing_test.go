@@ -0,0 +1,27 @@
+package signing
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestUnsignedLayout(t *testing.T) {
+	contents := []byte{1, 2, 3}
+	signed, err := SignFile(context.Background(), Unsigned{}, contents)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(signed) != signaturePadding+SignatureSize+len(contents) {
+		t.Fatalf("len = %d", len(signed))
+	}
+
+	if !bytes.Equal(signed[:signaturePadding+SignatureSize], make([]byte, signaturePadding+SignatureSize)) {
+		t.Error("padding and signature area are not zeroed")
+	}
+
+	if !bytes.Equal(signed[signaturePadding+SignatureSize:], contents) {
+		t.Error("contents were not appended")
+	}
+}
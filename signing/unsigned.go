@@ -0,0 +1,14 @@
+package signing
+
+import "context"
+
+// SignatureSize is the size of a signature made with the 2048-bit keys the channel uses.
+const SignatureSize = 256
+
+// Unsigned fills the signature area with zeroes. Only patched channels and emulators that skip
+// signature verification will accept files signed this way.
+type Unsigned struct{}
+
+func (Unsigned) SignDigest(_ context.Context, _ []byte) ([]byte, error) {
+	return make([]byte, SignatureSize), nil
+}
@@ -0,0 +1,116 @@
+// Package sink delivers generated files to where the console fetches them from: a directory served
+// by a web server, an S3-compatible bucket or a WebDAV server.
+package sink
+
+import (
+	"WiiNewsPR/generator"
+	"WiiNewsPR/objstore"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Sink is an output that files can also be read back from, to check what it holds.
+type Sink interface {
+	generator.OutputSink
+	// Read returns the file stored under key. A missing file is an error matching fs.ErrNotExist.
+	Read(ctx context.Context, key string) ([]byte, error)
+}
+
+// Open returns an object storage sink for an s3://bucket/prefix URL, a WebDAV sink for an http or
+// https URL and a directory sink for anything else.
+func Open(location string) (Sink, error) {
+	switch {
+	case objstore.IsURL(location):
+		client, prefix, err := objstore.FromURL(location)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjectSink(client, prefix), nil
+	case strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://"):
+		return NewWebDAVSink(location)
+	default:
+		return NewDirSink(location), nil
+	}
+}
+
+// DirSink writes files below a directory.
+type DirSink struct {
+	dir string
+}
+
+func NewDirSink(dir string) *DirSink {
+	return &DirSink{dir: dir}
+}
+
+// Write replaces the file through a temporary one, so a web server never serves half a file.
+func (s *DirSink) Write(_ context.Context, key string, data []byte) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return "", err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err = temp.Write(data); err != nil {
+		temp.Close()
+		return "", err
+	}
+
+	if err = temp.Close(); err != nil {
+		return "", err
+	}
+
+	if err = os.Chmod(temp.Name(), 0644); err != nil {
+		return "", err
+	}
+
+	if err = os.Rename(temp.Name(), path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (s *DirSink) Read(_ context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(key)))
+}
+
+// ObjectSink uploads files to S3-compatible object storage, under a key prefix. A single PUT
+// replaces an object atomically, so there is no temporary object.
+type ObjectSink struct {
+	client *objstore.Client
+	prefix string
+}
+
+// NewObjectSink uploads to prefix + key. A non-empty prefix should end in a slash.
+func NewObjectSink(client *objstore.Client, prefix string) *ObjectSink {
+	return &ObjectSink{client: client, prefix: prefix}
+}
+
+// Write uploads the file and returns its s3:// URL.
+func (s *ObjectSink) Write(ctx context.Context, key string, data []byte) (string, error) {
+	if _, err := s.client.Put(ctx, s.prefix+key, data, objstore.PutOptions{ContentType: "application/octet-stream"}); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s://%s/%s%s", objstore.Scheme, s.client.Bucket, s.prefix, key), nil
+}
+
+func (s *ObjectSink) Read(ctx context.Context, key string) ([]byte, error) {
+	object, err := s.client.Get(ctx, s.prefix+key)
+	if errors.Is(err, objstore.ErrNotFound) {
+		return nil, fmt.Errorf("%s%s: %w", s.prefix, key, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return object.Data, nil
+}
@@ -0,0 +1,62 @@
+package sink
+
+import (
+	"WiiNewsPR/objstore/objstoretest"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDirSink(t *testing.T) {
+	dir := t.TempDir()
+	s := NewDirSink(dir)
+
+	for _, data := range []string{"first", "second"} {
+		location, err := s.Write(context.Background(), "v2/1/049/news.bin.18", []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := filepath.Join(dir, "v2", "1", "049", "news.bin.18"); location != want {
+			t.Errorf("location = %q, want %q", location, want)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "v2", "1", "049", "news.bin.18"))
+	if err != nil || string(data) != "second" {
+		t.Fatalf("data = %q, %v", data, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "v2", "1", "049"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("entries = %v, %v, want no temporary files left", entries, err)
+	}
+}
+
+func TestObjectSink(t *testing.T) {
+	server := objstoretest.NewServer("news")
+	defer server.Close()
+
+	s := NewObjectSink(server.Client(), "files/")
+	location, err := s.Write(context.Background(), "v2/1/049/news.bin.18", []byte("file"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location != "s3://news/files/v2/1/049/news.bin.18" {
+		t.Errorf("location = %q", location)
+	}
+
+	if data, ok := server.Object("files/v2/1/049/news.bin.18"); !ok || string(data) != "file" {
+		t.Errorf("object = %q", data)
+	}
+
+	if data, err := s.Read(context.Background(), "v2/1/049/news.bin.18"); err != nil || string(data) != "file" {
+		t.Errorf("read = %q, %v", data, err)
+	}
+
+	if _, err := s.Read(context.Background(), "v2/1/049/news.bin.19"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist for a missing object", err)
+	}
+}
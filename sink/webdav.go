@@ -0,0 +1,186 @@
+package sink
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// WebDAVSink uploads files to a WebDAV server, such as Nextcloud, Apache mod_dav or nginx with
+// the dav module. A file is uploaded under a temporary name and then moved over the old one, so
+// clients never download half a file.
+type WebDAVSink struct {
+	base     *url.URL
+	username string
+	password string
+	client   *http.Client
+}
+
+// NewWebDAVSink uploads below the collection at rawURL. Credentials come from the URL, or from
+// WIINEWSPR_WEBDAV_USER and WIINEWSPR_WEBDAV_PASSWORD, and are sent with basic authentication.
+func NewWebDAVSink(rawURL string) (*WebDAVSink, error) {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("invalid WebDAV location %q, want an http or https URL", rawURL)
+	}
+
+	s := &WebDAVSink{
+		username: os.Getenv("WIINEWSPR_WEBDAV_USER"),
+		password: os.Getenv("WIINEWSPR_WEBDAV_PASSWORD"),
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+
+	if base.User != nil {
+		s.username = base.User.Username()
+		s.password, _ = base.User.Password()
+		base.User = nil
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+	s.base = base
+
+	return s, nil
+}
+
+// url returns the URL of the file at the slash-separated path below the collection.
+func (s *WebDAVSink) url(key string) string {
+	return s.base.ResolveReference(&url.URL{Path: strings.TrimPrefix(key, "/")}).String()
+}
+
+func (s *WebDAVSink) do(ctx context.Context, method, target string, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+
+	return s.client.Do(req)
+}
+
+// expect closes resp and fails unless its status is one of codes.
+func expect(resp *http.Response, codes ...int) error {
+	defer resp.Body.Close()
+
+	for _, code := range codes {
+		if resp.StatusCode == code {
+			return nil
+		}
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("WebDAV %s %s returned %d: %s", resp.Request.Method, resp.Request.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// Write uploads the file to a temporary name and moves it into place. Missing collections are
+// created on the way.
+func (s *WebDAVSink) Write(ctx context.Context, key string, data []byte) (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+
+	dir, name := path.Split(key)
+	temp := s.url(dir + "." + name + "." + hex.EncodeToString(suffix) + ".tmp")
+	target := s.url(key)
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+
+	resp, err := s.do(ctx, http.MethodPut, temp, data, header)
+	if err != nil {
+		return "", err
+	}
+
+	// The server answers 409 Conflict when the parent collection doesn't exist.
+	if resp.StatusCode == http.StatusConflict {
+		resp.Body.Close()
+		if err = s.mkcol(ctx, dir); err != nil {
+			return "", err
+		}
+
+		if resp, err = s.do(ctx, http.MethodPut, temp, data, header); err != nil {
+			return "", err
+		}
+	}
+
+	if err = expect(resp, http.StatusCreated, http.StatusNoContent, http.StatusOK); err != nil {
+		return "", err
+	}
+
+	resp, err = s.do(ctx, "MOVE", temp, nil, http.Header{"Destination": {target}, "Overwrite": {"T"}})
+	if err == nil {
+		err = expect(resp, http.StatusCreated, http.StatusNoContent)
+	}
+	if err != nil {
+		// Don't leave the temporary file behind.
+		if resp, deleteErr := s.do(context.Background(), http.MethodDelete, temp, nil, nil); deleteErr == nil {
+			resp.Body.Close()
+		}
+		return "", err
+	}
+
+	return target, nil
+}
+
+// mkcol creates the collection at dir and any missing parents.
+func (s *WebDAVSink) mkcol(ctx context.Context, dir string) error {
+	var current string
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if part == "" {
+			continue
+		}
+		current += part + "/"
+
+		resp, err := s.do(ctx, "MKCOL", s.url(current), nil, nil)
+		if err != nil {
+			return err
+		}
+
+		// 405 Method Not Allowed means the collection already exists.
+		if err = expect(resp, http.StatusCreated, http.StatusMethodNotAllowed); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WebDAVSink) Read(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.url(key), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", key, fs.ErrNotExist)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, expect(resp, http.StatusOK)
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
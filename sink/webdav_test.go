@@ -0,0 +1,168 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// davServer is an in-memory WebDAV server with the few methods the sink uses.
+type davServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	files       map[string][]byte
+	collections map[string]bool
+}
+
+func newDAVServer(t *testing.T) *davServer {
+	t.Helper()
+
+	s := &davServer{files: map[string][]byte{}, collections: map[string]bool{"/dav/": true}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *davServer) paths() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paths []string
+	for p := range s.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (s *davServer) serve(w http.ResponseWriter, r *http.Request) {
+	if user, password, ok := r.BasicAuth(); !ok || user != "wii" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	parent := path.Dir(strings.TrimSuffix(r.URL.Path, "/")) + "/"
+	switch r.Method {
+	case http.MethodGet:
+		data, ok := s.files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	case http.MethodPut:
+		if !s.collections[parent] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.files[r.URL.Path], _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+	case "MKCOL":
+		if s.collections[r.URL.Path] {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !s.collections[parent] {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.collections[r.URL.Path] = true
+		w.WriteHeader(http.StatusCreated)
+	case "MOVE":
+		destination, err := url.Parse(r.Header.Get("Destination"))
+		data, ok := s.files[r.URL.Path]
+		if err != nil || !ok || r.Header.Get("Overwrite") != "T" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, existed := s.files[destination.Path]
+		s.files[destination.Path] = data
+		delete(s.files, r.URL.Path)
+		if existed {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		delete(s.files, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func TestWebDAVSink(t *testing.T) {
+	server := newDAVServer(t)
+	ctx := context.Background()
+
+	s, err := NewWebDAVSink(strings.Replace(server.URL, "http://", "http://wii:secret@", 1) + "/dav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, data := range []string{"first", "second"} {
+		location, err := s.Write(ctx, "v2/1/049/news.bin.18", []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := server.URL + "/dav/v2/1/049/news.bin.18"; location != want {
+			t.Errorf("location = %q, want %q", location, want)
+		}
+	}
+
+	// Only the file is left, without the temporary upload or the credentials in the location.
+	if paths := server.paths(); len(paths) != 1 || paths[0] != "/dav/v2/1/049/news.bin.18" {
+		t.Errorf("paths = %v", paths)
+	}
+
+	if data, err := s.Read(ctx, "v2/1/049/news.bin.18"); err != nil || string(data) != "second" {
+		t.Errorf("read = %q, %v", data, err)
+	}
+
+	if _, err = s.Read(ctx, "v2/1/049/news.bin.19"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("err = %v, want fs.ErrNotExist", err)
+	}
+}
+
+func TestWebDAVSinkRejected(t *testing.T) {
+	server := newDAVServer(t)
+
+	s, err := NewWebDAVSink(server.URL + "/dav/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = s.Write(context.Background(), "news.bin.18", []byte("file")); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("err = %v, want the 401 reported", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	for location, want := range map[string]string{
+		"./out":                "*sink.DirSink",
+		"s3://bucket/prefix":   "*sink.ObjectSink",
+		"https://dav.example/": "*sink.WebDAVSink",
+	} {
+		s, err := Open(location)
+		if err != nil {
+			t.Fatalf("Open(%q): %v", location, err)
+		}
+		if got := fmt.Sprintf("%T", s); got != want {
+			t.Errorf("Open(%q) = %s, want %s", location, got, want)
+		}
+	}
+}